@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/phachon/go-logger"
+)
+
+func main() {
+
+	logger := go_logger.NewLogger()
+
+	syslogConfig := &go_logger.SyslogConfig{
+		Network:  go_logger.SYSLOG_NETWORK_UDP,
+		Address:  "127.0.0.1:514",
+		RFC:      go_logger.SYSLOG_RFC5424,
+		Facility: "local0",
+		AppName:  "example",
+		MsgId:    "login",
+	}
+	logger.Attach("syslog", go_logger.LOGGER_LEVEL_DEBUG, syslogConfig)
+
+	logger.Info("login ok", "user", "phachon")
+	logger.Error("login failed", "user", "phachon", "reason", "password")
+
+	logger.Flush()
+}
@@ -12,7 +12,9 @@ const API_ADAPTER_NAME = "api"
 
 // adapter api
 type AdapterApi struct {
-	config *ApiConfig
+	config      *ApiConfig
+	timeEncoder *timeEncoder
+	encoder     Encoder
 }
 
 // api config
@@ -23,6 +25,8 @@ type ApiConfig struct {
 
 	// request method
 	// GET, POST
+	// if async BatchSize > 1, a batch of messages is sent by one POST request,
+	// the request body is a json array of messages, Content-Type is application/json
 	Method string
 
 	// request headers
@@ -33,6 +37,28 @@ type ApiConfig struct {
 
 	// verify response http code
 	VerifyCode int
+
+	// request body encoder name, "text", "json", "logfmt", "ecs", "gelf" or a name of RegisterEncoder
+	// if encoder is empty, messages are sent as request params, the batch as a json array
+	// if encoder is set, Method must be POST, a message is sent as the encoded request body,
+	// a batch is a json array if the encoder Content-Type is application/json, otherwise lines
+	Encoder string
+
+	// text encoder format, see ConsoleConfig.Format
+	Format string
+
+	// time param and time json key layout, default time.RFC3339Nano
+	TimeLayout string
+
+	// time param and time json key time zone, "UTC", "Local" or IANA name as "Asia/Shanghai", default "Local"
+	TimeZone string
+
+	// time param precision and time_unix param unit, "s", "ms", "us", "ns", default "ms"
+	TimePrecision string
+
+	// json encoder keys renamed, selected, omitted, nested and constant keys, see JsonSchema
+	// if JsonSchema is set and Encoder is empty, Encoder is "json"
+	JsonSchema *JsonSchema
 }
 
 func (ac *ApiConfig) Name() string {
@@ -62,10 +88,39 @@ func (adapterApi *AdapterApi) Init(apiConfig Config) error {
 	if adapterApi.config.IsVerify && (adapterApi.config.VerifyCode == 0) {
 		return errors.New("config if IsVerify is true, VerifyCode cannot be 0!")
 	}
+	timeEncoder, err := newTimeEncoder(ac.TimeLayout, ac.TimeZone, ac.TimePrecision)
+	if err != nil {
+		return err
+	}
+	adapterApi.timeEncoder = timeEncoder
+
+	if ac.Encoder == "" && ac.JsonSchema != nil {
+		ac.Encoder = ENCODER_JSON
+	}
+	if ac.Encoder != "" {
+		if ac.Method != "POST" {
+			return errors.New("config if Encoder is set, Method must be 'POST'!")
+		}
+		encoder, err := newEncoder(ac.Encoder, false, &EncoderConfig{
+			Format:        ac.Format,
+			TimeLayout:    ac.TimeLayout,
+			TimeZone:      ac.TimeZone,
+			TimePrecision: ac.TimePrecision,
+			JsonSchema:    ac.JsonSchema,
+		})
+		if err != nil {
+			return err
+		}
+		adapterApi.encoder = encoder
+	}
 	return nil
 }
 
-func (adapterApi *AdapterApi) Write(loggerMsg *loggerMessage) error {
+func (adapterApi *AdapterApi) Write(loggerMsg *Entry) error {
+
+	if adapterApi.encoder != nil {
+		return adapterApi.postBody(adapterApi.encoder.Encode(nil, loggerMsg))
+	}
 
 	url := adapterApi.config.Url
 	method := adapterApi.config.Method
@@ -74,16 +129,38 @@ func (adapterApi *AdapterApi) Write(loggerMsg *loggerMessage) error {
 	headers := adapterApi.config.Headers
 
 	loggerMap := map[string]string{
-		"timestamp":          strconv.FormatInt(loggerMsg.Timestamp, 10),
-		"timestamp_format":   loggerMsg.TimestampFormat,
-		"millisecond":        strconv.FormatInt(loggerMsg.Millisecond, 10),
-		"millisecond_format": loggerMsg.MillisecondFormat,
-		"level":              strconv.Itoa(loggerMsg.Level),
-		"level_string":       loggerMsg.LevelString,
-		"body":               loggerMsg.Body,
-		"file":               loggerMsg.File,
-		"line":               strconv.Itoa(loggerMsg.Line),
-		"function":           loggerMsg.Function,
+		"timestamp":          strconv.FormatInt(loggerMsg.timestamp(), 10),
+		"timestamp_format":   loggerMsg.timestampFormat(),
+		"millisecond":        strconv.FormatInt(loggerMsg.millisecond(), 10),
+		"millisecond_format": loggerMsg.millisecondFormat(),
+		"time":               string(adapterApi.timeEncoder.appendTime(nil, loggerMsg.time)),
+		"time_unix":          strconv.FormatInt(adapterApi.timeEncoder.unix(loggerMsg.time), 10),
+		"level":              strconv.Itoa(loggerMsg.level),
+		"level_string":       loggerMsg.LevelString(),
+		"body":               loggerMsg.body,
+		"file":               loggerMsg.file,
+		"line":               strconv.Itoa(loggerMsg.line),
+		"function":           loggerMsg.function,
+		"path":               loggerMsg.path,
+		"rel_path":           loggerMsg.RelativePath(),
+		"package":            loggerMsg.Package(),
+		"short_function":     loggerMsg.ShortFunction(),
+		"hostname":           processHostname,
+		"pid":                strconv.Itoa(processPid),
+		"program":            processProgram,
+		"sequence":           strconv.FormatUint(loggerMsg.sequence, 10),
+	}
+	if loggerMsg.goroutine != 0 {
+		loggerMap["goroutine"] = strconv.FormatUint(loggerMsg.goroutine, 10)
+	}
+	if loggerMsg.stack != "" {
+		loggerMap["stack"] = loggerMsg.stack
+	}
+	for _, token := range loadTokens().custom {
+		loggerMap[token.name] = token.value(loggerMsg)
+	}
+	for _, field := range loggerMsg.fields {
+		loggerMap[field.outputKey()] = field.String()
 	}
 
 	var err error
@@ -103,6 +180,65 @@ func (adapterApi *AdapterApi) Write(loggerMsg *loggerMessage) error {
 	return nil
 }
 
+func (adapterApi *AdapterApi) WriteBatch(entries []*Entry) error {
+
+	if adapterApi.encoder != nil && adapterApi.contentType() != "application/json" {
+		body := []byte{}
+		for i, loggerMsg := range entries {
+			if i > 0 {
+				body = append(body, '\n')
+			}
+			body = adapterApi.encoder.Encode(body, loggerMsg)
+		}
+		return adapterApi.postBody(body)
+	}
+
+	body := []byte{'['}
+	for i, loggerMsg := range entries {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		if adapterApi.encoder != nil {
+			body = adapterApi.encoder.Encode(body, loggerMsg)
+		} else {
+			body = appendJSON(body, loggerMsg, adapterApi.timeEncoder)
+		}
+	}
+	body = append(body, ']')
+
+	return adapterApi.postBody(body)
+}
+
+// post encoded body, Content-Type of the encoder or application/json of a json array
+func (adapterApi *AdapterApi) postBody(body []byte) error {
+
+	url := adapterApi.config.Url
+	isVerify := adapterApi.config.IsVerify
+	verifyCode := adapterApi.config.VerifyCode
+	headers := adapterApi.config.Headers
+
+	_, code, err := utils.NewMisc().HttpPostBody(url, body, adapterApi.contentType(), headers, 0)
+	if err != nil {
+		return err
+	}
+	if isVerify && (code != verifyCode) {
+		return fmt.Errorf("%s", "request "+url+" faild, code="+strconv.Itoa(code))
+	}
+
+	return nil
+}
+
+// request body Content-Type of the encoder
+func (adapterApi *AdapterApi) contentType() string {
+	if adapterApi.encoder == nil {
+		return "application/json"
+	}
+	if encoder, ok := adapterApi.encoder.(ContentTypeEncoder); ok {
+		return encoder.ContentType()
+	}
+	return "text/plain; charset=utf-8"
+}
+
 func (adapterApi *AdapterApi) Flush() {
 
 }
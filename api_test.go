@@ -0,0 +1,88 @@
+package go_logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdapterApi_Name(t *testing.T) {
+	apiAdapter := NewAdapterApi()
+
+	if apiAdapter.Name() != API_ADAPTER_NAME {
+		t.Error("api adapter name error")
+	}
+}
+
+func TestAdapterApi_Write(t *testing.T) {
+
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+	}))
+	defer server.Close()
+
+	apiAdapter := NewAdapterApi()
+	err := apiAdapter.Init(&ApiConfig{
+		Url:        server.URL,
+		Method:     "GET",
+		IsVerify:   true,
+		VerifyCode: 200,
+		TimeZone:   "UTC",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = apiAdapter.Write(NewEntry(LOGGER_LEVEL_INFO, "logger api adapter write", "user", "phachon"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if query["body"][0] != "logger api adapter write" || query["user"][0] != "phachon" || query["level_string"][0] != "Info" {
+		t.Errorf("api adapter write params error, got %v", query)
+	}
+	if !strings.HasSuffix(query["time"][0], "Z") || query["time_unix"][0] == "" {
+		t.Errorf("api adapter write time params error, got %v", query)
+	}
+	if query["test_region"][0] != "eu-Info" {
+		t.Errorf("api adapter write custom token params error, got %v", query)
+	}
+}
+
+func TestAdapterApi_WriteBatch(t *testing.T) {
+
+	var messages []map[string]interface{}
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &messages)
+	}))
+	defer server.Close()
+
+	apiAdapter := NewAdapterApi()
+	err := apiAdapter.Init(&ApiConfig{
+		Url:    server.URL,
+		Method: "POST",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = apiAdapter.(LoggerBatchWriter).WriteBatch([]*Entry{
+		NewEntry(LOGGER_LEVEL_INFO, "message 1"),
+		NewEntry(LOGGER_LEVEL_ERROR, "message 2", "user", "phachon"),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if contentType != "application/json" || len(messages) != 2 {
+		t.Fatalf("api adapter write batch error, got %s %v", contentType, messages)
+	}
+	if messages[1]["body"] != "message 2" || messages[1]["user"] != "phachon" {
+		t.Errorf("api adapter write batch message error, got %v", messages[1])
+	}
+}
@@ -0,0 +1,419 @@
+package go_logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ASYNC_POLICY_BLOCK         = "block"
+	ASYNC_POLICY_BLOCK_TIMEOUT = "block_timeout"
+	ASYNC_POLICY_DROP_NEWEST   = "drop_newest"
+	ASYNC_POLICY_DROP_OLDEST   = "drop_oldest"
+)
+
+// async config
+type AsyncConfig struct {
+
+	// message channel length, default 100
+	ChanLen int
+
+	// policy when the message channel is full, default "block"
+	// "block" wait until the message is queued
+	// "block_timeout" wait Timeout, then drop the message
+	// "drop_newest" drop the message
+	// "drop_oldest" drop the oldest queued message, then queue the message
+	Policy string
+
+	// "block_timeout" policy wait time, default 100ms
+	Timeout time.Duration
+
+	// the worker writes messages in batches of BatchSize, default 1 is not batched
+	// adapters implement LoggerBatchWriter receive a batch by one WriteBatch
+	BatchSize int
+
+	// max wait time of a batch not full, default 100ms
+	BatchLinger time.Duration
+
+	// dropped messages are reported as a warning message every ReportInterval and at Flush, Close
+	// default 0 is one minute, < 0 is only reported at Flush, Close
+	ReportInterval time.Duration
+}
+
+var asyncPolicies = map[string]bool{
+	ASYNC_POLICY_BLOCK:         true,
+	ASYNC_POLICY_BLOCK_TIMEOUT: true,
+	ASYNC_POLICY_DROP_NEWEST:   true,
+	ASYNC_POLICY_DROP_OLDEST:   true,
+}
+
+// async queue, a worker goroutine writes queued messages
+type asyncQueue struct {
+	pending    int64                         // queued and not written messages, read and written atomically
+	dropped    [LOGGER_LEVEL_DEBUG + 1]int64 // dropped messages by level, read and written atomically
+	reported   [LOGGER_LEVEL_DEBUG + 1]int64 // dropped messages already reported, worker only
+	stopping   int32                         // 1 is stop called, read and written atomically
+	config     AsyncConfig                   // async config
+	msgChan    chan *Entry                   // message channel
+	signalChan chan asyncSignal              // worker signal
+	stopped    chan struct{}                 // closed after the worker stops
+	closing    <-chan struct{}               // closed when the logger is closing, blocked push returns
+	name       string                        // output name, empty is logger queue
+	write      func(entries []*Entry)        // write a batch of messages
+	flush      func()                        // flush after signal
+}
+
+// async worker signal, the worker writes all queued messages then closes done
+type asyncSignal struct {
+	stop bool
+	done chan struct{}
+}
+
+//check async config and set default values
+//params : config *AsyncConfig
+//return : AsyncConfig, error
+func checkAsyncConfig(config *AsyncConfig) (AsyncConfig, error) {
+	asyncConfig := AsyncConfig{}
+	if config != nil {
+		asyncConfig = *config
+	}
+	if asyncConfig.ChanLen <= 0 {
+		asyncConfig.ChanLen = 100
+	}
+	if asyncConfig.Policy == "" {
+		asyncConfig.Policy = ASYNC_POLICY_BLOCK
+	}
+	if !asyncPolicies[asyncConfig.Policy] {
+		return asyncConfig, errors.New("config Policy must be one of the 'block', 'block_timeout', 'drop_newest', 'drop_oldest'!")
+	}
+	if asyncConfig.Timeout <= 0 {
+		asyncConfig.Timeout = 100 * time.Millisecond
+	}
+	if asyncConfig.BatchSize <= 0 {
+		asyncConfig.BatchSize = 1
+	}
+	if asyncConfig.BatchLinger <= 0 {
+		asyncConfig.BatchLinger = 100 * time.Millisecond
+	}
+	if asyncConfig.ReportInterval == 0 {
+		asyncConfig.ReportInterval = time.Minute
+	}
+	return asyncConfig, nil
+}
+
+//new async queue and start the worker
+//params : config checked by checkAsyncConfig, closing chan of the logger, write func, flush func
+//return : *asyncQueue
+func newAsyncQueue(config AsyncConfig, closing <-chan struct{}, write func(entries []*Entry), flush func()) *asyncQueue {
+	queue := &asyncQueue{
+		config:     config,
+		msgChan:    make(chan *Entry, config.ChanLen),
+		signalChan: make(chan asyncSignal),
+		stopped:    make(chan struct{}),
+		closing:    closing,
+		write:      write,
+		flush:      flush,
+	}
+	go func() {
+		defer func() {
+			e := recover()
+			if e != nil {
+				fmt.Fprintf(os.Stderr, "logger: async worker stopped by panic: %v\n", e)
+			}
+		}()
+		queue.start()
+	}()
+	return queue
+}
+
+//queue a message by policy, the queue holds a reference of the message until it is written or dropped
+//params : loggerMsg *Entry
+//return : error, ErrMessageDropped if the message is dropped, ErrLoggerClosed if the logger is closing
+func (queue *asyncQueue) push(loggerMsg *Entry) error {
+	return queue.enqueue(loggerMsg, queue.closing)
+}
+
+//queue a message from the logger worker, it is not discarded when the logger is closing
+//the worker writes all queued messages before the output queues stop
+//params : loggerMsg *Entry
+//return : error, ErrMessageDropped if the message is dropped
+func (queue *asyncQueue) pushWait(loggerMsg *Entry) error {
+	return queue.enqueue(loggerMsg, nil)
+}
+
+//queue a message by policy, a blocked push returns when closing is closed, a nil closing never returns
+func (queue *asyncQueue) enqueue(loggerMsg *Entry, closing <-chan struct{}) error {
+	atomic.AddInt64(&queue.pending, 1)
+	loggerMsg.retain()
+	if queue.isStopped() {
+		queue.drop(loggerMsg)
+		return ErrMessageDropped
+	}
+
+	switch queue.config.Policy {
+	case ASYNC_POLICY_BLOCK_TIMEOUT:
+		select {
+		case queue.msgChan <- loggerMsg:
+			return queue.sent()
+		default:
+		}
+		timer := time.NewTimer(queue.config.Timeout)
+		defer timer.Stop()
+		select {
+		case queue.msgChan <- loggerMsg:
+			return queue.sent()
+		case <-timer.C:
+		case <-closing:
+			return queue.discard(loggerMsg)
+		}
+	case ASYNC_POLICY_DROP_NEWEST:
+		select {
+		case queue.msgChan <- loggerMsg:
+			return queue.sent()
+		default:
+		}
+	case ASYNC_POLICY_DROP_OLDEST:
+		for {
+			select {
+			case queue.msgChan <- loggerMsg:
+				return queue.sent()
+			default:
+			}
+			select {
+			case oldMsg := <-queue.msgChan:
+				queue.drop(oldMsg)
+			default:
+			}
+		}
+	default:
+		select {
+		case queue.msgChan <- loggerMsg:
+			return queue.sent()
+		default:
+		}
+		select {
+		case queue.msgChan <- loggerMsg:
+			return queue.sent()
+		case <-queue.stopped:
+		case <-closing:
+			return queue.discard(loggerMsg)
+		}
+	}
+
+	queue.drop(loggerMsg)
+	return ErrMessageDropped
+}
+
+//a message sent while the queue stops may be left after the worker stopped, the left messages are dropped
+func (queue *asyncQueue) sent() error {
+	select {
+	case <-queue.stopped:
+		queue.dropQueued()
+	default:
+	}
+	return nil
+}
+
+//drop the messages left in the channel after the worker stopped
+func (queue *asyncQueue) dropQueued() {
+	for {
+		select {
+		case loggerMsg := <-queue.msgChan:
+			queue.drop(loggerMsg)
+		default:
+			return
+		}
+	}
+}
+
+//count a dropped message and release it
+func (queue *asyncQueue) drop(loggerMsg *Entry) {
+	atomic.AddInt64(&queue.pending, -1)
+	atomic.AddInt64(&queue.dropped[loggerMsg.level], 1)
+	loggerMsg.release()
+}
+
+//release a message not queued because the logger is closing, it is not counted as dropped
+func (queue *asyncQueue) discard(loggerMsg *Entry) error {
+	atomic.AddInt64(&queue.pending, -1)
+	loggerMsg.release()
+	return ErrLoggerClosed
+}
+
+//start write by read msgChan, until a stop signal
+//messages are written when the batch is full, BatchLinger after the first message of the batch, or at signal
+func (queue *asyncQueue) start() {
+	var reportChan <-chan time.Time
+	if queue.config.ReportInterval > 0 {
+		ticker := time.NewTicker(queue.config.ReportInterval)
+		defer ticker.Stop()
+		reportChan = ticker.C
+	}
+
+	batch := make([]*Entry, 0, queue.config.BatchSize)
+	var lingerTimer *time.Timer
+	var lingerChan <-chan time.Time
+	writeBatch := func() {
+		if lingerTimer != nil {
+			lingerTimer.Stop()
+			lingerTimer = nil
+			lingerChan = nil
+		}
+		if len(batch) == 0 {
+			return
+		}
+		queue.safeWrite(batch)
+		atomic.AddInt64(&queue.pending, -int64(len(batch)))
+		for i := range batch {
+			batch[i].release()
+			batch[i] = nil
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case loggerMsg := <-queue.msgChan:
+			batch = append(batch, loggerMsg)
+			if len(batch) >= queue.config.BatchSize {
+				writeBatch()
+			} else if lingerTimer == nil {
+				lingerTimer = time.NewTimer(queue.config.BatchLinger)
+				lingerChan = lingerTimer.C
+			}
+		case <-lingerChan:
+			writeBatch()
+		case <-reportChan:
+			queue.report()
+		case signal := <-queue.signalChan:
+			for len(queue.msgChan) > 0 {
+				batch = append(batch, <-queue.msgChan)
+				if len(batch) >= queue.config.BatchSize {
+					writeBatch()
+				}
+			}
+			writeBatch()
+			queue.report()
+			if !signal.stop {
+				queue.safeFlush()
+			}
+			close(signal.done)
+			if signal.stop {
+				return
+			}
+		}
+	}
+}
+
+//write a warning message of the dropped messages since the last report
+func (queue *asyncQueue) report() {
+	total := int64(0)
+	fields := []Field{}
+	for level := range queue.dropped {
+		dropped := atomic.LoadInt64(&queue.dropped[level])
+		if dropped == queue.reported[level] {
+			continue
+		}
+		total += dropped - queue.reported[level]
+		fields = append(fields, Field{
+			Key:   "dropped_" + strings.ToLower(levelStringMapping[level]),
+			Value: dropped - queue.reported[level],
+		})
+		queue.reported[level] = dropped
+	}
+	if total == 0 {
+		return
+	}
+	if queue.name != "" {
+		fields = append(fields, Field{Key: "output", Value: queue.name})
+	}
+
+	loggerMsg := getEntry()
+	loggerMsg.setTime(time.Now())
+	loggerMsg.level = LOGGER_LEVEL_WARNING
+	loggerMsg.body = "logger: " + strconv.FormatInt(total, 10) + " messages dropped by async policy " + queue.config.Policy
+	loggerMsg.function = "go_logger.asyncQueue.report"
+	loggerMsg.fields = fields
+	queue.safeWrite([]*Entry{loggerMsg})
+	loggerMsg.release()
+}
+
+//write a batch of messages, a panic of the adapter is written to stderr and the worker keeps running
+func (queue *asyncQueue) safeWrite(entries []*Entry) {
+	defer queue.recoverPanic("write")
+	queue.write(entries)
+}
+
+//flush the outputs, a panic of the adapter is written to stderr and the worker keeps running
+func (queue *asyncQueue) safeFlush() {
+	defer queue.recoverPanic("flush")
+	queue.flush()
+}
+
+//recover a panic of the adapter and write it to stderr
+func (queue *asyncQueue) recoverPanic(action string) {
+	e := recover()
+	if e == nil {
+		return
+	}
+	if queue.name != "" {
+		fmt.Fprintf(os.Stderr, "logger: async %s panic of output:%v, error: %v\n", action, queue.name, e)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "logger: async %s panic, error: %v\n", action, e)
+}
+
+//stop the worker, queued messages are written before stop
+//only the first call stops the worker, later calls return without waiting
+func (queue *asyncQueue) stop() {
+	if !atomic.CompareAndSwapInt32(&queue.stopping, 0, 1) {
+		return
+	}
+	done := make(chan struct{})
+	queue.signalChan <- asyncSignal{stop: true, done: done}
+	<-done
+	close(queue.stopped)
+	queue.dropQueued()
+}
+
+//stop is called, the worker is stopped or writing queued messages before stop
+func (queue *asyncQueue) isStopped() bool {
+	return atomic.LoadInt32(&queue.stopping) == 1
+}
+
+//write queued messages and flush, return when ctx is done
+//return : undelivered int, error ctx.Err()
+func (queue *asyncQueue) flushContext(ctx context.Context) (int, error) {
+	done := make(chan struct{})
+	select {
+	case queue.signalChan <- asyncSignal{done: done}:
+	case <-ctx.Done():
+		return queue.undelivered(), ctx.Err()
+	}
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+		return queue.undelivered(), ctx.Err()
+	}
+}
+
+//the number of queued messages not written
+func (queue *asyncQueue) undelivered() int {
+	return int(atomic.LoadInt64(&queue.pending))
+}
+
+//the number of dropped messages by level
+func (queue *asyncQueue) droppedCount() map[int]int64 {
+	dropped := map[int]int64{}
+	for level := range queue.dropped {
+		dropped[level] = atomic.LoadInt64(&queue.dropped[level])
+	}
+	return dropped
+}
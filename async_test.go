@@ -0,0 +1,374 @@
+package go_logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckAsyncConfig(t *testing.T) {
+
+	asyncConfig, err := checkAsyncConfig(nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if asyncConfig.ChanLen != 100 || asyncConfig.Policy != ASYNC_POLICY_BLOCK || asyncConfig.ReportInterval != time.Minute {
+		t.Errorf("check async config default error, got %+v", asyncConfig)
+	}
+	_, err = checkAsyncConfig(&AsyncConfig{Policy: "drop"})
+	if err == nil {
+		t.Error("check async config policy error")
+	}
+}
+
+func testAsyncPolicyLogger(t *testing.T, policy string) (*Logger, chan struct{}) {
+	release := make(chan struct{})
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: release})
+	err := logger.SetAsyncConfig(&AsyncConfig{
+		ChanLen:        2,
+		Policy:         policy,
+		Timeout:        10 * time.Millisecond,
+		ReportInterval: -1,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return logger, release
+}
+
+func TestLogger_AsyncDropNewest(t *testing.T) {
+
+	logger, release := testAsyncPolicyLogger(t, ASYNC_POLICY_DROP_NEWEST)
+
+	dropped := 0
+	for i := 0; i < 10; i++ {
+		if logger.Writer(LOGGER_LEVEL_INFO, "message") == ErrMessageDropped {
+			dropped++
+		}
+	}
+	if dropped < 7 || logger.Dropped()[LOGGER_LEVEL_INFO] != int64(dropped) {
+		t.Errorf("async drop newest error, dropped %d, got %v", dropped, logger.Dropped())
+	}
+
+	close(release)
+	logger.Flush()
+	adapter := logger.Output("test_block").(*testBlockAdapter)
+	if len(adapter.bodies) != 10-dropped+1 {
+		t.Fatalf("async drop newest write error, got %v", adapter.bodies)
+	}
+	report := adapter.bodies[len(adapter.bodies)-1]
+	if !strings.Contains(report, "messages dropped by async policy drop_newest") {
+		t.Errorf("async drop report error, got %s", report)
+	}
+	logger.Close()
+}
+
+func TestLogger_AsyncDropOldest(t *testing.T) {
+
+	logger, release := testAsyncPolicyLogger(t, ASYNC_POLICY_DROP_OLDEST)
+
+	for i := 0; i < 10; i++ {
+		err := logger.Writer(LOGGER_LEVEL_INFO, "message "+string(rune('0'+i)))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	close(release)
+	logger.Flush()
+	adapter := logger.Output("test_block").(*testBlockAdapter)
+	bodies := strings.Join(adapter.bodies, ",")
+	if !strings.Contains(bodies, "message 8,message 9") || logger.Dropped()[LOGGER_LEVEL_INFO] < 7 {
+		t.Errorf("async drop oldest error, got %s", bodies)
+	}
+	logger.Close()
+}
+
+func TestLogger_AsyncBlockTimeout(t *testing.T) {
+
+	logger, release := testAsyncPolicyLogger(t, ASYNC_POLICY_BLOCK_TIMEOUT)
+
+	dropped := 0
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if logger.Writer(LOGGER_LEVEL_INFO, "message") == ErrMessageDropped {
+			dropped++
+		}
+	}
+	if dropped < 2 || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("async block timeout error, dropped %d", dropped)
+	}
+	close(release)
+	logger.Close()
+}
+
+func TestLogger_SetOutputAsync(t *testing.T) {
+
+	release := make(chan struct{})
+	logger := NewLogger()
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+	logger.AttachNamed("slow", "test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: release})
+
+	err := logger.SetOutputAsync("slow", &AsyncConfig{ChanLen: 10, Policy: ASYNC_POLICY_DROP_NEWEST, ReportInterval: -1})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := logger.SetOutputAsync("file", nil).(*UnknownOutputError); !ok {
+		t.Error("set output async unknown output error")
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.Info("message")
+	}
+	if strings.Count(buf.String(), "message") != 5 {
+		t.Errorf("slow async output must not block console output, got %s", buf.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	undelivered, err := logger.FlushContext(ctx)
+	if err != context.DeadlineExceeded || undelivered != 5 {
+		t.Errorf("flush async output timeout error, got %d %v", undelivered, err)
+	}
+	dropped, err := logger.OutputDropped("slow")
+	if err != nil || dropped[LOGGER_LEVEL_INFO] != 0 {
+		t.Errorf("async output dropped error, got %v %v", dropped, err)
+	}
+
+	close(release)
+	err = logger.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	adapter := logger.Output("slow").(*testBlockAdapter)
+	if len(adapter.bodies) != 5 {
+		t.Errorf("close must write async output queue, got %v", adapter.bodies)
+	}
+}
+
+func TestLogger_CloseAsyncOutputDelivered(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.AttachNamed("count", "test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: closedChan})
+	err := logger.SetAsyncConfig(&AsyncConfig{ChanLen: 2000})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = logger.SetOutputAsync("count", &AsyncConfig{ChanLen: 2000})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := 0; i < 1000; i++ {
+		logger.Info("message")
+	}
+	err = logger.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	adapter := logger.Output("count").(*testBlockAdapter)
+	if atomic.LoadInt64(&adapter.count) != 1000 {
+		t.Errorf("close must deliver all async messages, got %d", atomic.LoadInt64(&adapter.count))
+	}
+}
+
+func TestLogger_DetachAsyncOutput(t *testing.T) {
+
+	release := make(chan struct{})
+	close(release)
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.AttachNamed("slow", "test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: release})
+	logger.SetOutputAsync("slow", nil)
+	adapter := logger.Output("slow").(*testBlockAdapter)
+
+	logger.Info("message")
+	logger.Detach("slow")
+	if len(adapter.bodies) != 1 {
+		t.Errorf("detach must write async output queue, got %v", adapter.bodies)
+	}
+	logger.Info("detached message")
+	logger.Close()
+}
+
+func TestLogger_DetachAsyncOutputAfterClose(t *testing.T) {
+
+	logger := NewLogger()
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = &bytes.Buffer{}
+	logger.SetOutputAsync("console", nil)
+	logger.Info("message")
+	logger.Close()
+
+	detached := make(chan error, 1)
+	go func() {
+		detached <- logger.Detach("console")
+	}()
+	select {
+	case err := <-detached:
+		if err != nil {
+			t.Errorf("detach async output after close error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("detach async output after close must not block")
+	}
+}
+
+func TestAsyncQueue_PushStopped(t *testing.T) {
+
+	policies := []string{ASYNC_POLICY_BLOCK, ASYNC_POLICY_BLOCK_TIMEOUT, ASYNC_POLICY_DROP_NEWEST, ASYNC_POLICY_DROP_OLDEST}
+	for _, policy := range policies {
+		asyncConfig, _ := checkAsyncConfig(&AsyncConfig{Policy: policy})
+		queue := newAsyncQueue(asyncConfig, nil, func(entries []*Entry) {}, func() {})
+		queue.stop()
+
+		loggerMsg := getEntry()
+		loggerMsg.level = LOGGER_LEVEL_INFO
+		err := queue.push(loggerMsg)
+		loggerMsg.release()
+		if err != ErrMessageDropped {
+			t.Errorf("push stopped queue %s error, got %v", policy, err)
+		}
+		if queue.undelivered() != 0 || queue.droppedCount()[LOGGER_LEVEL_INFO] != 1 {
+			t.Errorf("push stopped queue %s must count dropped, got %d %v", policy, queue.undelivered(), queue.droppedCount())
+		}
+	}
+}
+
+// adapter records the size of every write
+type testBatchAdapter struct {
+	lock    sync.Mutex
+	batches []int
+}
+
+func (a *testBatchAdapter) Init(config Config) error {
+	return nil
+}
+
+func (a *testBatchAdapter) Write(entry *Entry) error {
+	return a.WriteBatch([]*Entry{entry})
+}
+
+func (a *testBatchAdapter) WriteBatch(entries []*Entry) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.batches = append(a.batches, len(entries))
+	return nil
+}
+
+func (a *testBatchAdapter) Flush() {
+}
+
+func (a *testBatchAdapter) Name() string {
+	return "test_batch"
+}
+
+func init() {
+	Register("test_batch", func() LoggerAbstract {
+		return &testBatchAdapter{}
+	})
+}
+
+// adapter panics when the message body is "panic"
+type testPanicAdapter struct {
+	count int64
+}
+
+func (a *testPanicAdapter) Init(config Config) error {
+	return nil
+}
+
+func (a *testPanicAdapter) Write(entry *Entry) error {
+	if entry.Body() == "panic" {
+		panic("test panic")
+	}
+	atomic.AddInt64(&a.count, 1)
+	return nil
+}
+
+func (a *testPanicAdapter) Flush() {
+}
+
+func (a *testPanicAdapter) Name() string {
+	return "test_panic"
+}
+
+func init() {
+	Register("test_panic", func() LoggerAbstract {
+		return &testPanicAdapter{}
+	})
+}
+
+func TestLogger_AsyncAdapterPanic(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_panic", LOGGER_LEVEL_DEBUG, &ConsoleConfig{})
+	logger.SetAsync()
+
+	done := make(chan struct{})
+	go func() {
+		logger.Info("panic")
+		logger.Info("message")
+		logger.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("close must return after an adapter panic")
+	}
+	adapter := logger.Output("test_panic").(*testPanicAdapter)
+	if atomic.LoadInt64(&adapter.count) != 1 {
+		t.Errorf("async worker must write messages after an adapter panic, got %d", adapter.count)
+	}
+}
+
+func TestLogger_AsyncBatch(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_batch", LOGGER_LEVEL_INFO, &ConsoleConfig{})
+	err := logger.SetAsyncConfig(&AsyncConfig{
+		ChanLen:     100,
+		BatchSize:   4,
+		BatchLinger: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	adapter := logger.Output("test_batch").(*testBatchAdapter)
+
+	for i := 0; i < 10; i++ {
+		logger.Info("message")
+		logger.Debug("filtered message")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	adapter.lock.Lock()
+	total := 0
+	for _, size := range adapter.batches {
+		if size > 4 {
+			t.Errorf("async batch size error, got %v", adapter.batches)
+		}
+		total += size
+	}
+	batches := len(adapter.batches)
+	adapter.lock.Unlock()
+	if total != 10 || batches >= 10 {
+		t.Errorf("async batch linger error, got %v", adapter.batches)
+	}
+
+	logger.Info("message")
+	logger.Close()
+	if len(adapter.batches) != batches+1 {
+		t.Errorf("close must write the batch, got %v", adapter.batches)
+	}
+}
@@ -1,12 +1,15 @@
 package go_logger
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 )
 
 // go test -run=benchmark -cpu=1,2,4 -benchmem -benchtime=3s -bench="ConsoleText"
 func BenchmarkLoggerConsoleText(b *testing.B) {
 	logger := NewLogger()
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -20,6 +23,7 @@ func BenchmarkLoggerConsoleAsyncText(b *testing.B) {
 	logger := NewLogger()
 	logger.SetAsync()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -36,6 +40,7 @@ func BenchmarkLoggerConsoleJson(b *testing.B) {
 	logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{
 		JsonFormat: true,
 	})
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -46,12 +51,15 @@ func BenchmarkLoggerConsoleJson(b *testing.B) {
 
 // go test -run=benchmark -cpu=1,2,4 -benchmem -benchtime=3s -bench="FileText"
 func BenchmarkLoggerFileText(b *testing.B) {
+	dir, remove := testTempDir(b)
+	defer remove()
 	logger := NewLogger()
 	logger.Detach("console")
 	logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{
-		Filename:  "./test.log",
+		Filename:  filepath.Join(dir, "test.log"),
 		DateSlice: "d",
 	})
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -62,14 +70,17 @@ func BenchmarkLoggerFileText(b *testing.B) {
 
 // go test -run=benchmark -cpu=1,2,4 -benchmem -benchtime=3s -bench="AsyncText"
 func BenchmarkLoggerFileAsyncText(b *testing.B) {
+	dir, remove := testTempDir(b)
+	defer remove()
 	logger := NewLogger()
 	logger.Detach("console")
 	logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{
-		Filename:  "./test.log",
+		Filename:  filepath.Join(dir, "test.log"),
 		DateSlice: "d",
 	})
 	logger.SetAsync()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -81,13 +92,16 @@ func BenchmarkLoggerFileAsyncText(b *testing.B) {
 
 // go test -run=benchmark -cpu=1,2,4 -benchmem -benchtime=3s -bench="FileJson"
 func BenchmarkLoggerFileJson(b *testing.B) {
+	dir, remove := testTempDir(b)
+	defer remove()
 	logger := NewLogger()
 	logger.Detach("console")
 	logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{
-		Filename:   "./test.log",
+		Filename:   filepath.Join(dir, "test.log"),
 		DateSlice:  "d",
 		JsonFormat: true,
 	})
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -95,3 +109,91 @@ func BenchmarkLoggerFileJson(b *testing.B) {
 		}
 	})
 }
+
+// logger writes console to ioutil.Discard, allocations of the logger without io
+func newBenchmarkDiscardLogger(config *ConsoleConfig) *Logger {
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("console", LOGGER_LEVEL_DEBUG, config)
+	logger.Output("console").(*AdapterConsole).write.writer = ioutil.Discard
+	return logger
+}
+
+// go test -run=benchmark -benchmem -bench="DiscardText"
+func BenchmarkLoggerDiscardText(b *testing.B) {
+	logger := newBenchmarkDiscardLogger(&ConsoleConfig{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark logger message")
+	}
+}
+
+// go test -run=benchmark -benchmem -bench="DiscardTextFields"
+func BenchmarkLoggerDiscardTextFields(b *testing.B) {
+	logger := newBenchmarkDiscardLogger(&ConsoleConfig{}).With("service", "api")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark logger message", "user", "phachon", "ok", true)
+	}
+}
+
+// go test -run=benchmark -benchmem -bench="DiscardJson"
+func BenchmarkLoggerDiscardJson(b *testing.B) {
+	logger := newBenchmarkDiscardLogger(&ConsoleConfig{JsonFormat: true})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark logger message", "user", "phachon")
+	}
+}
+
+// go test -run=benchmark -benchmem -bench="DiscardJsonSchema"
+func BenchmarkLoggerDiscardJsonSchema(b *testing.B) {
+	logger := newBenchmarkDiscardLogger(&ConsoleConfig{
+		JsonFormat: true,
+		JsonSchema: &JsonSchema{
+			Keys: map[string]string{"time": "@timestamp", "body": "message", "level_string": "log.level", "fields": "labels"},
+			Only: []string{"time", "level_string", "body", "fields"},
+		},
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark logger message", "user", "phachon")
+	}
+}
+
+// go test -run=benchmark -benchmem -bench="DiscardLogfmt"
+func BenchmarkLoggerDiscardLogfmt(b *testing.B) {
+	logger := newBenchmarkDiscardLogger(&ConsoleConfig{Encoder: ENCODER_LOGFMT})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark logger message", "user", "phachon")
+	}
+}
+
+// go test -run=benchmark -benchmem -bench="DiscardAsyncText"
+func BenchmarkLoggerDiscardAsyncText(b *testing.B) {
+	logger := newBenchmarkDiscardLogger(&ConsoleConfig{})
+	logger.SetAsync()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark logger message")
+	}
+	logger.Flush()
+}
+
+// go test -run=benchmark -benchmem -bench="Disabled"
+func BenchmarkLoggerDisabled(b *testing.B) {
+	logger := newBenchmarkDiscardLogger(&ConsoleConfig{})
+	logger.SetLevel(LOGGER_LEVEL_INFO)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debugf("benchmark logger message %d", i)
+	}
+}
@@ -0,0 +1,34 @@
+package go_logger
+
+import (
+	"sync"
+)
+
+// max capacity of a pooled buffer, larger buffers are released to gc
+const maxPooledBufferSize = 64 * 1024
+
+// encoded message buffer
+type byteBuffer struct {
+	bs []byte
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &byteBuffer{bs: make([]byte, 0, 1024)}
+	},
+}
+
+// get an empty buffer of the pool
+func getBuffer() *byteBuffer {
+	buf := bufferPool.Get().(*byteBuffer)
+	buf.bs = buf.bs[:0]
+	return buf
+}
+
+// put the buffer back to the pool, the buffer must not be used after put
+func putBuffer(buf *byteBuffer) {
+	if cap(buf.bs) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
@@ -24,8 +24,9 @@ var levelColors = map[int]color.Attribute{
 
 // adapter console
 type AdapterConsole struct {
-	write  *ConsoleWriter
-	config *ConsoleConfig
+	write   *ConsoleWriter
+	config  *ConsoleConfig
+	encoder Encoder
 }
 
 // console writer
@@ -39,10 +40,14 @@ type ConsoleConfig struct {
 	// console text is show color
 	Color bool
 
+	// output encoder name, "text", "json", "logfmt", "ecs", "gelf" or a name of RegisterEncoder
+	// if encoder is empty, "json" if JsonFormat is true, otherwise "text"
+	Encoder string
+
 	// is json format
 	JsonFormat bool
 
-	// jsonFormat is false, please input format string
+	// text encoder format string
 	// if format is empty, default format "%millisecond_format% [%level_string%] %body%"
 	//
 	//  Timestamp "%timestamp%"
@@ -55,9 +60,31 @@ type ConsoleConfig struct {
 	//	File string "%file%"
 	//	Line int "%line%"
 	//	Function "%function%"
+	//	Time "%time%", TimeUnix "%time_unix%", see TimeLayout, TimeZone, TimePrecision
+	//	Path "%path%", RelativePath "%rel_path%", Package "%package%", ShortFunction "%short_function%" of the caller
+	//	Hostname "%hostname%", Pid "%pid%", Program "%program%"
+	//	Sequence "%sequence%", Goroutine "%goroutine%", see Logger.SetGoroutineId
+	//	Stack "%stack%", empty if not captured, see Logger.SetStackLevel
+	//	custom tokens of RegisterToken
+	//	Fields "%fields%", if not in format, fields key=value pairs are appended to the end
+	//
+	// format is compiled at Init, unknown tokens are an error, see CompileFormat for "%%" and width modifiers
 	//
 	// example: format = "%millisecond_format% [%level_string%] %body%"
 	Format string
+
+	// %time% token and time json key layout, default time.RFC3339Nano
+	TimeLayout string
+
+	// %time% token and time json key time zone, "UTC", "Local" or IANA name as "Asia/Shanghai", default "Local"
+	TimeZone string
+
+	// %time% precision and %time_unix% unit, "s", "ms", "us", "ns", default "ms"
+	TimePrecision string
+
+	// json encoder keys renamed, selected, omitted, nested and constant keys, nil is the keys of Entry.MarshalJSON
+	// if JsonSchema is set and Encoder is empty, Encoder is "json"
+	JsonSchema *JsonSchema
 }
 
 func (cc *ConsoleConfig) Name() string {
@@ -84,35 +111,46 @@ func (adapterConsole *AdapterConsole) Init(consoleConfig Config) error {
 	cc := vc.Interface().(*ConsoleConfig)
 	adapterConsole.config = cc
 
-	if cc.JsonFormat == false && cc.Format == "" {
+	if cc.Encoder == "" && cc.JsonSchema != nil {
+		cc.Encoder = ENCODER_JSON
+	}
+	if cc.Encoder == "" && cc.JsonFormat == false && cc.Format == "" {
 		cc.Format = defaultLoggerMessageFormat
 	}
+	encoder, err := newEncoder(cc.Encoder, cc.JsonFormat, &EncoderConfig{
+		Format:        cc.Format,
+		TimeLayout:    cc.TimeLayout,
+		TimeZone:      cc.TimeZone,
+		TimePrecision: cc.TimePrecision,
+		JsonSchema:    cc.JsonSchema,
+	})
+	if err != nil {
+		return err
+	}
+	adapterConsole.encoder = encoder
 
 	return nil
 }
 
-func (adapterConsole *AdapterConsole) Write(loggerMsg *loggerMessage) error {
+func (adapterConsole *AdapterConsole) Write(loggerMsg *Entry) error {
 
-	msg := ""
-	if adapterConsole.config.JsonFormat == true {
-		//jsonByte, _ := json.Marshal(loggerMsg)
-		jsonByte, _ := loggerMsg.MarshalJSON()
-		msg = string(jsonByte)
-	} else {
-		msg = loggerMessageFormat(adapterConsole.config.Format, loggerMsg)
-	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.bs = adapterConsole.encoder.Encode(buf.bs, loggerMsg)
 	consoleWriter := adapterConsole.write
 
 	if adapterConsole.config.Color {
-		colorAttr := adapterConsole.getColorByLevel(loggerMsg.Level, msg)
+		msg := string(buf.bs)
+		colorAttr := adapterConsole.getColorByLevel(loggerMsg.level, msg)
 		consoleWriter.lock.Lock()
 		color.New(colorAttr).Println(msg)
 		consoleWriter.lock.Unlock()
 		return nil
 	}
 
+	buf.bs = append(buf.bs, '\n')
 	consoleWriter.lock.Lock()
-	consoleWriter.writer.Write([]byte(msg + "\n"))
+	consoleWriter.writer.Write(buf.bs)
 	consoleWriter.lock.Unlock()
 
 	return nil
@@ -35,17 +35,13 @@ func TestAdapterConsole_WriteColor(t *testing.T) {
 	}
 	consoleAdapter.Init(consoleConfig)
 
-	loggerMsg := &loggerMessage{
-		Timestamp:         time.Now().Unix(),
-		TimestampFormat:   time.Now().Format("2006-01-02 15:04:05"),
-		Millisecond:       time.Now().UnixNano() / 1e6,
-		MillisecondFormat: time.Now().Format("2006-01-02 15:04:05.999"),
-		Level:             LOGGER_LEVEL_DEBUG,
-		LevelString:       "debug",
-		Body:              "logger console adapter test color",
-		File:              "console_test.go",
-		Line:              50,
-		Function:          "TestAdapterConsole_WriteIsColor",
+	loggerMsg := &Entry{
+		time:     time.Now(),
+		level:    LOGGER_LEVEL_DEBUG,
+		body:     "logger console adapter test color",
+		file:     "console_test.go",
+		line:     50,
+		function: "TestAdapterConsole_WriteIsColor",
 	}
 	err := consoleAdapter.Write(loggerMsg)
 	if err != nil {
@@ -62,20 +58,25 @@ func TestAdapterConsole_WriteJsonFormat(t *testing.T) {
 	}
 	consoleAdapter.Init(consoleConfig)
 
-	loggerMsg := &loggerMessage{
-		Timestamp:         time.Now().Unix(),
-		TimestampFormat:   time.Now().Format("2006-01-02 15:04:05"),
-		Millisecond:       time.Now().UnixNano() / 1e6,
-		MillisecondFormat: time.Now().Format("2006-01-02 15:04:05.999"),
-		Level:             LOGGER_LEVEL_DEBUG,
-		LevelString:       "debug",
-		Body:              "logger console adapter test jsonFormat",
-		File:              "console_test.go",
-		Line:              77,
-		Function:          "TestAdapterConsole_WriteJsonFormat",
+	loggerMsg := &Entry{
+		time:     time.Now(),
+		level:    LOGGER_LEVEL_DEBUG,
+		body:     "logger console adapter test jsonFormat",
+		file:     "console_test.go",
+		line:     77,
+		function: "TestAdapterConsole_WriteJsonFormat",
 	}
 	err := consoleAdapter.Write(loggerMsg)
 	if err != nil {
 		t.Error(err.Error())
 	}
 }
+
+func TestAdapterConsole_InitFormatError(t *testing.T) {
+
+	consoleAdapter := NewAdapterConsole()
+	err := consoleAdapter.Init(&ConsoleConfig{Format: "%millisecond_format% [%level%] %bodyy%"})
+	if _, ok := err.(*FormatError); !ok {
+		t.Errorf("console adapter init must return *FormatError, got %v", err)
+	}
+}
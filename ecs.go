@@ -0,0 +1,120 @@
+package go_logger
+
+import (
+	"github.com/mailru/easyjson/buffer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// ecs.version of the ecs encoder
+const ECS_VERSION = "1.6.0"
+
+var ecsLevelMapping = map[int]string{
+	LOGGER_LEVEL_EMERGENCY: "emergency",
+	LOGGER_LEVEL_ALERT:     "alert",
+	LOGGER_LEVEL_CRITICAL:  "critical",
+	LOGGER_LEVEL_ERROR:     "error",
+	LOGGER_LEVEL_WARNING:   "warning",
+	LOGGER_LEVEL_NOTICE:    "notice",
+	LOGGER_LEVEL_INFO:      "info",
+	LOGGER_LEVEL_DEBUG:     "debug",
+}
+
+// ecs top level keys, a field with the same key is renamed to "fields.key"
+var ecsReservedKeys = map[string]bool{
+	"@timestamp":        true,
+	"log":               true,
+	"log.level":         true,
+	"log.origin":        true,
+	"message":           true,
+	"ecs":               true,
+	"ecs.version":       true,
+	"host":              true,
+	"host.hostname":     true,
+	"process":           true,
+	"process.pid":       true,
+	"process.name":      true,
+	"process.thread.id": true,
+	"error":             true,
+	"error.stack_trace": true,
+}
+
+// Elastic Common Schema encoder, json of the ecs logging format
+//
+//	{"@timestamp":"2018-03-23T14:55:07.003Z","log.level":"info","message":"login ok","ecs.version":"1.6.0",
+//	"log.origin":{"file.name":"main.go","file.line":12,"function":"main.main"},
+//	"host.hostname":"web-1","process.pid":1024,"process.name":"app","user":"phachon"}
+//
+// goroutine is "process.thread.id" and stack is "error.stack_trace" if captured,
+// custom tokens and fields are top level keys, time zone is "UTC" if the config TimeZone is empty
+type ecsEncoder struct {
+	timeEncoder *timeEncoder
+	reserved    map[string]bool
+}
+
+func newECSEncoder(config *EncoderConfig) (Encoder, error) {
+	timeZone := config.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	timeEncoder, err := newTimeEncoder(config.TimeLayout, timeZone, config.TimePrecision)
+	if err != nil {
+		return nil, err
+	}
+	reserved := map[string]bool{}
+	for key := range ecsReservedKeys {
+		reserved[key] = true
+	}
+	for _, token := range loadTokens().custom {
+		reserved[token.name] = true
+	}
+	return &ecsEncoder{timeEncoder: timeEncoder, reserved: reserved}, nil
+}
+
+func (encoder *ecsEncoder) Encode(dst []byte, entry *Entry) []byte {
+	out := jwriter.Writer{Buffer: buffer.Buffer{Buf: dst}}
+	out.RawString(`{"@timestamp":"`)
+	start := len(out.Buffer.Buf)
+	out.Buffer.Buf = encoder.timeEncoder.appendTime(out.Buffer.Buf, entry.time)
+	closeJSONString(&out, start)
+	out.RawString(`,"log.level":`)
+	out.String(ecsLevelMapping[entry.level])
+	out.RawString(`,"message":`)
+	out.String(entry.body)
+	out.RawString(`,"ecs.version":"` + ECS_VERSION + `","log.origin":{"file.name":`)
+	out.String(entry.file)
+	out.RawString(`,"file.line":`)
+	out.Int(entry.line)
+	out.RawString(`,"function":`)
+	out.String(entry.function)
+	out.RawString(`},"host.hostname":`)
+	out.String(processHostname)
+	out.RawString(`,"process.pid":`)
+	out.Int(processPid)
+	out.RawString(`,"process.name":`)
+	out.String(processProgram)
+	if entry.goroutine != 0 {
+		out.RawString(`,"process.thread.id":`)
+		out.Uint64(entry.goroutine)
+	}
+	if entry.stack != "" {
+		out.RawString(`,"error.stack_trace":`)
+		out.String(entry.stack)
+	}
+	for _, token := range loadTokens().custom {
+		out.RawByte(',')
+		out.String(token.name)
+		out.RawByte(':')
+		out.String(token.value(entry))
+	}
+	encodeFieldsJSON(&out, entry.fields, encoder.reserved, false)
+	out.RawByte('}')
+	return out.Buffer.BuildBytes()
+}
+
+func (encoder *ecsEncoder) ContentType() string {
+	return "application/json"
+}
+
+func init() {
+	RegisterEncoder(ENCODER_ECS, newECSEncoder)
+}
@@ -0,0 +1,34 @@
+package go_logger
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestECSEncoder_Encode(t *testing.T) {
+
+	encoder, err := newEncoder(ENCODER_ECS, false, &EncoderConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	entry := NewEntry(LOGGER_LEVEL_WARNING, "login failed", "user", "phachon", "message", "field")
+	entry.setTime(time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.FixedZone("CST", 8*3600)))
+	entry.file = "main.go"
+	entry.line = 12
+	entry.function = "main.main"
+	entry.stack = "goroutine 1 [running]:"
+
+	str := string(encoder.Encode(nil, entry))
+	want := `{"@timestamp":"2018-03-23T06:55:07.003Z","log.level":"warning","message":"login failed","ecs.version":"1.6.0",` +
+		`"log.origin":{"file.name":"main.go","file.line":12,"function":"main.main"},` +
+		`"host.hostname":` + strconv.Quote(processHostname) + `,"process.pid":` + strconv.Itoa(processPid) + `,"process.name":` + strconv.Quote(processProgram) +
+		`,"error.stack_trace":"goroutine 1 [running]:","test_region":"eu-Warning","user":"phachon","fields.message":"field"}`
+	if str != want {
+		t.Errorf("ecs encoder error, want %s got %s", want, str)
+	}
+	if !json.Valid([]byte(str)) {
+		t.Errorf("ecs encoder json is not valid, got %s", str)
+	}
+}
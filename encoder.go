@@ -0,0 +1,296 @@
+package go_logger
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	ENCODER_TEXT   = "text"
+	ENCODER_JSON   = "json"
+	ENCODER_LOGFMT = "logfmt"
+	ENCODER_ECS    = "ecs"
+	ENCODER_GELF   = "gelf"
+)
+
+// entry encoder of an output, appends the encoded entry to dst without a line break
+// entries are pooled and must not be retained after Encode returns
+type Encoder interface {
+	Encode(dst []byte, entry *Entry) []byte
+}
+
+// optional encoder interface, Content-Type of the api adapter request body
+// encoders without it are sent as "text/plain; charset=utf-8"
+type ContentTypeEncoder interface {
+	ContentType() string
+}
+
+// encoder config, set from the output config
+type EncoderConfig struct {
+
+	// text encoder format, default "%millisecond_format% [%level_string%] %body%", see ConsoleConfig.Format
+	Format string
+
+	// time layout, default time.RFC3339Nano
+	TimeLayout string
+
+	// time zone, "UTC", "Local" or IANA name as "Asia/Shanghai", default "Local"
+	TimeZone string
+
+	// time precision, "s", "ms", "us", "ns", default "ms"
+	TimePrecision string
+
+	// json encoder schema, nil is the keys of Entry.MarshalJSON
+	JsonSchema *JsonSchema
+}
+
+type encoderFunc func(config *EncoderConfig) (Encoder, error)
+
+var encoders = make(map[string]encoderFunc)
+
+// Register output encoder, outputs select it by the config Encoder name
+// params : encoderName, newEncoder func(config *EncoderConfig) (Encoder, error)
+func RegisterEncoder(encoderName string, newEncoder encoderFunc) {
+	if encoders[encoderName] != nil {
+		panic("logger: encoder " + encoderName + " already registered!")
+	}
+	if newEncoder == nil {
+		panic("logger: encoder " + encoderName + " is nil!")
+	}
+
+	encoders[encoderName] = newEncoder
+}
+
+// new encoder of output config, encoder name is empty is "json" if jsonFormat, otherwise "text"
+// params : encoderName, jsonFormat, config
+// return : Encoder, error *UnknownEncoderError | encoder error
+func newEncoder(encoderName string, jsonFormat bool, config *EncoderConfig) (Encoder, error) {
+	if encoderName == "" {
+		encoderName = ENCODER_TEXT
+		if jsonFormat {
+			encoderName = ENCODER_JSON
+		}
+	}
+	newEncoder, ok := encoders[encoderName]
+	if !ok {
+		return nil, &UnknownEncoderError{Encoder: encoderName}
+	}
+	return newEncoder(config)
+}
+
+// Encode, text encoder is the compiled format
+func (template *FormatTemplate) Encode(dst []byte, entry *Entry) []byte {
+	return template.AppendFormat(dst, entry)
+}
+
+// new text encoder, format is compiled, unknown tokens are an error
+func newTextEncoder(config *EncoderConfig) (Encoder, error) {
+	timeEncoder, err := newTimeEncoder(config.TimeLayout, config.TimeZone, config.TimePrecision)
+	if err != nil {
+		return nil, err
+	}
+	format := config.Format
+	if format == "" {
+		format = defaultLoggerMessageFormat
+	}
+	template, err := CompileFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	template.timeEncoder = timeEncoder
+	return template, nil
+}
+
+// json encoder, keys of Entry.MarshalJSON, see JsonSchema for the keys of the schema
+type jsonEncoder struct {
+	timeEncoder *timeEncoder
+}
+
+func newJSONEncoder(config *EncoderConfig) (Encoder, error) {
+	timeEncoder, err := newTimeEncoder(config.TimeLayout, config.TimeZone, config.TimePrecision)
+	if err != nil {
+		return nil, err
+	}
+	if config.JsonSchema != nil {
+		return newJsonSchemaEncoder(config.JsonSchema, timeEncoder)
+	}
+	return &jsonEncoder{timeEncoder: timeEncoder}, nil
+}
+
+func (encoder *jsonEncoder) Encode(dst []byte, entry *Entry) []byte {
+	return appendJSON(dst, entry, encoder.timeEncoder)
+}
+
+func (encoder *jsonEncoder) ContentType() string {
+	return "application/json"
+}
+
+// logfmt encoder, key=value pairs
+//
+//	time=2018-03-23T14:55:07.003+08:00 level=Info msg="login ok" file=main.go line=12 function=main.main user=phachon
+//
+// goroutine and stack are written if set, then custom tokens and fields,
+// a field with the key of an entry pair is renamed to "fields.key"
+type logfmtEncoder struct {
+	timeEncoder *timeEncoder
+}
+
+func newLogfmtEncoder(config *EncoderConfig) (Encoder, error) {
+	timeEncoder, err := newTimeEncoder(config.TimeLayout, config.TimeZone, config.TimePrecision)
+	if err != nil {
+		return nil, err
+	}
+	return &logfmtEncoder{timeEncoder: timeEncoder}, nil
+}
+
+func (encoder *logfmtEncoder) Encode(dst []byte, entry *Entry) []byte {
+	dst = append(dst, "time="...)
+	start := len(dst)
+	dst = encoder.timeEncoder.appendTime(dst, entry.time)
+	dst = quoteLogfmtValue(dst, start)
+	dst = append(dst, " level="...)
+	dst = append(dst, entry.LevelString()...)
+	dst = append(dst, " msg="...)
+	dst = appendLogfmtString(dst, entry.body)
+	dst = append(dst, " file="...)
+	dst = appendLogfmtString(dst, entry.file)
+	dst = append(dst, " line="...)
+	dst = strconv.AppendInt(dst, int64(entry.line), 10)
+	dst = append(dst, " function="...)
+	dst = appendLogfmtString(dst, entry.function)
+	if entry.goroutine != 0 {
+		dst = append(dst, " goroutine="...)
+		dst = strconv.AppendUint(dst, entry.goroutine, 10)
+	}
+	if entry.stack != "" {
+		dst = append(dst, " stack="...)
+		dst = appendLogfmtString(dst, entry.stack)
+	}
+	for _, token := range loadTokens().custom {
+		dst = append(dst, ' ')
+		dst = appendLogfmtKey(dst, token.name)
+		dst = append(dst, '=')
+		dst = appendLogfmtString(dst, token.value(entry))
+	}
+	for _, field := range entry.fields {
+		dst = append(dst, ' ')
+		if field.Key == "msg" {
+			dst = append(dst, "fields."...)
+		}
+		dst = appendLogfmtKey(dst, field.outputKey())
+		dst = append(dst, '=')
+		start := len(dst)
+		dst = field.appendValue(dst)
+		dst = quoteLogfmtValue(dst, start)
+	}
+	return dst
+}
+
+func (encoder *logfmtEncoder) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// append logfmt key, space, '=', '"', control characters and invalid utf-8 are replaced by '_'
+func appendLogfmtKey(dst []byte, key string) []byte {
+	if key == "" {
+		return append(dst, '_')
+	}
+	for i, r := range key {
+		if !illegalLogfmtKeyRune(r) {
+			continue
+		}
+		dst = append(dst, key[:i]...)
+		for _, r := range key[i:] {
+			if illegalLogfmtKeyRune(r) {
+				r = '_'
+			}
+			buf := [utf8.UTFMax]byte{}
+			n := utf8.EncodeRune(buf[:], r)
+			dst = append(dst, buf[:n]...)
+		}
+		return dst
+	}
+	return append(dst, key...)
+}
+
+func illegalLogfmtKeyRune(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == 0x7f || r == utf8.RuneError
+}
+
+// append logfmt value, quoted if needed
+func appendLogfmtString(dst []byte, value string) []byte {
+	start := len(dst)
+	dst = append(dst, value...)
+	if !needLogfmtQuote(dst[start:]) {
+		return dst
+	}
+	return appendLogfmtQuoted(dst[:start], value)
+}
+
+// quote the value appended from start if needed
+func quoteLogfmtValue(dst []byte, start int) []byte {
+	if !needLogfmtQuote(dst[start:]) {
+		return dst
+	}
+	value := string(dst[start:])
+	return appendLogfmtQuoted(dst[:start], value)
+}
+
+// value is quoted if empty or has space, '=', '"', control characters or invalid utf-8
+func needLogfmtQuote(value []byte) bool {
+	if len(value) == 0 {
+		return true
+	}
+	ascii := true
+	for _, c := range value {
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			return true
+		}
+		if c >= utf8.RuneSelf {
+			ascii = false
+		}
+	}
+	return !ascii && !utf8.Valid(value)
+}
+
+// append quoted value, '"' and '\\' are escaped, control characters as \n, \r, \t or \u00XX,
+// invalid utf-8 is replaced by �
+func appendLogfmtQuoted(dst []byte, value string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(value); {
+		c := value[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(value[i:])
+			if r == utf8.RuneError && size == 1 {
+				dst = append(dst, `�`...)
+			} else {
+				dst = append(dst, value[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < ' ' || c == 0x7f:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+		i++
+	}
+	return append(dst, '"')
+}
+
+func init() {
+	RegisterEncoder(ENCODER_TEXT, newTextEncoder)
+	RegisterEncoder(ENCODER_JSON, newJSONEncoder)
+	RegisterEncoder(ENCODER_LOGFMT, newLogfmtEncoder)
+}
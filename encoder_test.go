@@ -0,0 +1,118 @@
+package go_logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLogfmtEncoder_Encode(t *testing.T) {
+
+	encoder, err := newEncoder(ENCODER_LOGFMT, false, &EncoderConfig{TimeZone: "UTC"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	entry := NewEntry(LOGGER_LEVEL_INFO, "login \"ok\"\n", "user", "pha chon", "msg", "", "a=b", "c\\d", "ms", 12)
+	entry.setTime(time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.UTC))
+	entry.file = "main.go"
+	entry.line = 12
+	entry.function = "main.main"
+
+	str := string(encoder.Encode(nil, entry))
+	want := `time=2018-03-23T14:55:07.003Z level=Info msg="login \"ok\"\n" file=main.go line=12 function=main.main test_region=eu-Info user="pha chon" fields.msg="" a_b=c\d ms=12`
+	if str != want {
+		t.Errorf("logfmt encoder error, want %s got %s", want, str)
+	}
+}
+
+func TestLogfmtEncoder_Escape(t *testing.T) {
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"phachon", `phachon`},
+		{"", `""`},
+		{"日志", `日志`},
+		{"a\tb\x01", `"a\tb\u0001"`},
+		{"a\xffb", `"a�b"`},
+	}
+	for _, test := range tests {
+		str := string(appendLogfmtString(nil, test.value))
+		if str != test.want {
+			t.Errorf("logfmt value %q error, want %s got %s", test.value, test.want, str)
+		}
+	}
+	if str := string(appendLogfmtKey(nil, "a b\"c")); str != "a_b_c" {
+		t.Errorf("logfmt key error, got %s", str)
+	}
+}
+
+// encoder of upper case body
+type upperTestEncoder struct{}
+
+func (encoder upperTestEncoder) Encode(dst []byte, entry *Entry) []byte {
+	for i := 0; i < len(entry.Body()); i++ {
+		c := entry.Body()[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+func init() {
+	RegisterEncoder("test_upper", func(config *EncoderConfig) (Encoder, error) {
+		return upperTestEncoder{}, nil
+	})
+}
+
+func TestRegisterEncoder(t *testing.T) {
+
+	var body string
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	apiAdapter := NewAdapterApi()
+	err := apiAdapter.Init(&ApiConfig{Url: server.URL, Method: "POST", Encoder: "test_upper"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = apiAdapter.(LoggerBatchWriter).WriteBatch([]*Entry{
+		NewEntry(LOGGER_LEVEL_INFO, "message 1"),
+		NewEntry(LOGGER_LEVEL_INFO, "message 2"),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if body != "MESSAGE 1\nMESSAGE 2" || contentType != "text/plain; charset=utf-8" {
+		t.Errorf("api adapter custom encoder error, got %s %q", contentType, body)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("register encoder twice must panic")
+		}
+	}()
+	RegisterEncoder(ENCODER_LOGFMT, newLogfmtEncoder)
+}
+
+func TestNewEncoderError(t *testing.T) {
+
+	err := NewAdapterConsole().Init(&ConsoleConfig{Encoder: "yaml"})
+	if _, ok := err.(*UnknownEncoderError); !ok {
+		t.Errorf("console adapter init must return *UnknownEncoderError, got %v", err)
+	}
+	err = NewAdapterApi().Init(&ApiConfig{Url: "http://127.0.0.1", Method: "GET", Encoder: ENCODER_LOGFMT})
+	if err == nil {
+		t.Error("api adapter encoder with GET must return error")
+	}
+}
@@ -0,0 +1,328 @@
+package go_logger
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mailru/easyjson/buffer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// logger entry, written by Logger to every adapter
+//
+// custom adapter example:
+//
+//	func (adapter *MyAdapter) Write(entry *go_logger.Entry) error {
+//		msg := go_logger.FormatEntry("%millisecond_format% [%level_string%] %body%", entry)
+//		...
+//	}
+type Entry struct {
+	time     time.Time
+	level    int
+	body     string
+	file     string
+	line     int
+	function string
+	path     string
+	stack    string
+	fields   []Field
+
+	goroutine uint64 // goroutine id, 0 is not captured
+	sequence  uint64 // sequence number of the logger
+
+	refs      int32    // references of a pooled entry, read and written atomically
+	fieldsBuf []Field  // reused fields of a pooled entry
+	timeBuf   [32]byte // millisecond format of time, formatted once
+	timeLen   int      // length of the millisecond format in timeBuf, 0 is not formatted
+}
+
+const (
+	timestampLayout   = "2006-01-02 15:04:05"
+	millisecondLayout = "2006-01-02 15:04:05.999"
+)
+
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return &Entry{}
+	},
+}
+
+// new entry with the current time, caller is empty
+// params : level int, body string, keyvals "key", value, ... or Fields
+// return : *Entry
+func NewEntry(level int, body string, keyvals ...interface{}) *Entry {
+	entry := &Entry{
+		level:  level,
+		body:   body,
+		fields: makeFields(keyvals),
+	}
+	entry.setTime(time.Now())
+	return entry
+}
+
+// get an entry of the pool, the caller owns one reference
+func getEntry() *Entry {
+	entry := entryPool.Get().(*Entry)
+	entry.refs = 1
+	return entry
+}
+
+// add a reference of a pooled entry, an async queue holds a reference until the entry is written
+func (entry *Entry) retain() {
+	atomic.AddInt32(&entry.refs, 1)
+}
+
+// release a reference of a pooled entry, the entry is reset and put back to the pool by the last reference
+func (entry *Entry) release() {
+	if atomic.AddInt32(&entry.refs, -1) != 0 {
+		return
+	}
+	fieldsBuf := entry.fieldsBuf
+	for i := range fieldsBuf {
+		fieldsBuf[i] = Field{}
+	}
+	*entry = Entry{fieldsBuf: fieldsBuf[:0]}
+	entryPool.Put(entry)
+}
+
+// set entry time, the millisecond format is formatted once for all outputs
+func (entry *Entry) setTime(t time.Time) {
+	entry.time = t
+	entry.timeLen = copy(entry.timeBuf[:], t.AppendFormat(entry.timeBuf[:0], millisecondLayout))
+}
+
+// entry time
+func (entry *Entry) Time() time.Time {
+	return entry.time
+}
+
+// entry level, LOGGER_LEVEL_EMERGENCY ... LOGGER_LEVEL_DEBUG
+func (entry *Entry) Level() int {
+	return entry.level
+}
+
+// entry level string, Emergency ... Debug
+func (entry *Entry) LevelString() string {
+	return levelStringMapping[entry.level]
+}
+
+// entry message body
+func (entry *Entry) Body() string {
+	return entry.body
+}
+
+// file name of the caller
+func (entry *Entry) File() string {
+	return entry.file
+}
+
+// line of the caller
+func (entry *Entry) Line() int {
+	return entry.line
+}
+
+// function name of the caller
+func (entry *Entry) Function() string {
+	return entry.function
+}
+
+// full path of the caller file
+func (entry *Entry) Path() string {
+	return entry.path
+}
+
+// path of the caller file relative to the module root of the caller package
+func (entry *Entry) RelativePath() string {
+	return string(appendRelativePath(nil, entry.path, entry.function))
+}
+
+// package path of the caller function
+func (entry *Entry) Package() string {
+	return functionPackage(entry.function)
+}
+
+// caller function name without package path
+func (entry *Entry) ShortFunction() string {
+	return shortFunction(entry.function)
+}
+
+// goroutine id of the caller, 0 if not captured, see Logger.SetGoroutineId
+func (entry *Entry) Goroutine() uint64 {
+	return entry.goroutine
+}
+
+// sequence number of the message, increased by every message of the logger and the loggers derived from it
+func (entry *Entry) Sequence() uint64 {
+	return entry.sequence
+}
+
+// stack trace of the caller, captured if level is at or above the logger stack level
+func (entry *Entry) Stack() string {
+	return entry.stack
+}
+
+// entry key/value fields, bound fields first, must not be modified
+func (entry *Entry) Fields() []Field {
+	return entry.fields
+}
+
+func (entry *Entry) timestamp() int64 {
+	return entry.time.Unix()
+}
+
+func (entry *Entry) timestampFormat() string {
+	return string(entry.appendTimestampFormat(nil))
+}
+
+func (entry *Entry) appendTimestampFormat(dst []byte) []byte {
+	if entry.timeLen >= len(timestampLayout) {
+		return append(dst, entry.timeBuf[:len(timestampLayout)]...)
+	}
+	return entry.time.AppendFormat(dst, timestampLayout)
+}
+
+func (entry *Entry) millisecond() int64 {
+	return entry.time.UnixNano() / 1e6
+}
+
+func (entry *Entry) millisecondFormat() string {
+	return string(entry.appendMillisecondFormat(nil))
+}
+
+func (entry *Entry) appendMillisecondFormat(dst []byte) []byte {
+	if entry.timeLen > 0 {
+		return append(dst, entry.timeBuf[:entry.timeLen]...)
+	}
+	return entry.time.AppendFormat(dst, millisecondLayout)
+}
+
+// format entry by %token% format string
+//
+//	Timestamp "%timestamp%"
+//	TimestampFormat "%timestamp_format%"
+//	Millisecond "%millisecond%"
+//	MillisecondFormat "%millisecond_format%"
+//	Level int "%level%"
+//	LevelString "%level_string%"
+//	Body string "%body%"
+//	File string "%file%"
+//	Line int "%line%"
+//	Function "%function%"
+//	Time "%time%", formatted by TimeLayout in TimeZone, truncated to TimePrecision of the output config
+//	TimeUnix "%time_unix%", unix time in TimePrecision unit
+//	Path "%path%", full path of the caller file
+//	RelativePath "%rel_path%", path of the caller file relative to the module root
+//	Package "%package%", package path of the caller function
+//	ShortFunction "%short_function%", caller function name without package path
+//	Hostname "%hostname%"
+//	Pid "%pid%"
+//	Program "%program%", base name of the program
+//	Sequence "%sequence%", sequence number of the message
+//	Goroutine "%goroutine%", 0 if not captured, see Logger.SetGoroutineId
+//	Stack "%stack%", empty if not captured
+//	custom tokens of RegisterToken
+//	Fields "%fields%", if not in format, fields key=value pairs are appended to the end
+//
+// tokens are replaced in format only, a token in message body is not replaced
+// unknown tokens are written as is, format is compiled by every call, adapters compile it once by CompileFormat
+//
+// example: format = "%millisecond_format% [%level_string%] %body%"
+func FormatEntry(format string, entry *Entry) string {
+	template, _ := compileFormat(format, false)
+	return template.Format(entry)
+}
+
+// MarshalJSON supports json.Marshaler interface
+// keys: timestamp, timestamp_format, millisecond, millisecond_format, time, time_unix, level, level_string, body, file, line, function,
+// path, rel_path, package, short_function, hostname, pid, program, sequence, goroutine and stack if captured,
+// custom tokens of RegisterToken, fields...
+func (entry *Entry) MarshalJSON() ([]byte, error) {
+	return appendJSON(nil, entry, defaultTimeEncoder), nil
+}
+
+// append entry json object to dst, see MarshalJSON
+// params : dst []byte, entry, timeEncoder of time and time_unix keys
+// return : []byte
+func appendJSON(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+	out := jwriter.Writer{Buffer: buffer.Buffer{Buf: dst}}
+	out.RawString(`{"timestamp":`)
+	out.Int64(entry.timestamp())
+	out.RawString(`,"timestamp_format":"`)
+	out.Buffer.Buf = entry.appendTimestampFormat(out.Buffer.Buf)
+	out.RawString(`","millisecond":`)
+	out.Int64(entry.millisecond())
+	out.RawString(`,"millisecond_format":"`)
+	out.Buffer.Buf = entry.appendMillisecondFormat(out.Buffer.Buf)
+	out.RawString(`","time":"`)
+	start := len(out.Buffer.Buf)
+	out.Buffer.Buf = timeEncoder.appendTime(out.Buffer.Buf, entry.time)
+	closeJSONString(&out, start)
+	out.RawString(`,"time_unix":`)
+	out.Int64(timeEncoder.unix(entry.time))
+	out.RawString(`,"level":`)
+	out.Int(entry.level)
+	out.RawString(`,"level_string":`)
+	out.String(entry.LevelString())
+	out.RawString(`,"body":`)
+	out.String(entry.body)
+	out.RawString(`,"file":`)
+	out.String(entry.file)
+	out.RawString(`,"line":`)
+	out.Int(entry.line)
+	out.RawString(`,"function":`)
+	out.String(entry.function)
+	out.RawString(`,"path":`)
+	out.String(entry.path)
+	out.RawString(`,"rel_path":"`)
+	start = len(out.Buffer.Buf)
+	out.Buffer.Buf = appendRelativePath(out.Buffer.Buf, entry.path, entry.function)
+	closeJSONString(&out, start)
+	out.RawString(`,"package":`)
+	out.String(functionPackage(entry.function))
+	out.RawString(`,"short_function":`)
+	out.String(shortFunction(entry.function))
+	out.RawString(`,"hostname":`)
+	out.String(processHostname)
+	out.RawString(`,"pid":`)
+	out.Int(processPid)
+	out.RawString(`,"program":`)
+	out.String(processProgram)
+	out.RawString(`,"sequence":`)
+	out.Uint64(entry.sequence)
+	if entry.goroutine != 0 {
+		out.RawString(`,"goroutine":`)
+		out.Uint64(entry.goroutine)
+	}
+	if entry.stack != "" {
+		out.RawString(`,"stack":`)
+		out.String(entry.stack)
+	}
+	registry := loadTokens()
+	for _, token := range registry.custom {
+		out.RawByte(',')
+		out.String(token.name)
+		out.RawByte(':')
+		out.String(token.value(entry))
+	}
+	encodeFieldsJSON(&out, entry.fields, registry.reserved, false)
+	out.RawByte('}')
+	return out.Buffer.BuildBytes()
+}
+
+// close json string of the bytes appended after the opening quote at start, the bytes are escaped if needed
+func closeJSONString(out *jwriter.Writer, start int) {
+	if bytes.IndexFunc(out.Buffer.Buf[start:], needJSONEscape) >= 0 {
+		value := string(out.Buffer.Buf[start:])
+		out.Buffer.Buf = out.Buffer.Buf[:start-1]
+		out.String(value)
+		return
+	}
+	out.RawByte('"')
+}
+
+// json string escapes '"', '\\' and control characters
+func needJSONEscape(r rune) bool {
+	return r == '"' || r == '\\' || r < 0x20
+}
@@ -0,0 +1,80 @@
+package go_logger
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewEntry(t *testing.T) {
+
+	entry := NewEntry(LOGGER_LEVEL_ERROR, "logger entry test", "user", "phachon")
+
+	if entry.Level() != LOGGER_LEVEL_ERROR || entry.LevelString() != "Error" {
+		t.Error("entry level error")
+	}
+	if entry.Body() != "logger entry test" {
+		t.Error("entry body error")
+	}
+	if entry.Time().IsZero() {
+		t.Error("entry time error")
+	}
+	if len(entry.Fields()) != 1 || entry.Fields()[0].Key != "user" {
+		t.Error("entry fields error")
+	}
+}
+
+func TestEntry_MarshalJSON(t *testing.T) {
+
+	entry := NewEntry(LOGGER_LEVEL_DEBUG, "logger entry json test")
+	entry.file = "entry_test.go"
+	entry.line = 30
+
+	jsonByte, err := entry.MarshalJSON()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	data := map[string]interface{}{}
+	err = json.Unmarshal(jsonByte, &data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	keys := []string{"timestamp", "timestamp_format", "millisecond", "millisecond_format", "time", "time_unix", "level", "level_string", "body", "file", "line", "function"}
+	for _, key := range keys {
+		if _, ok := data[key]; !ok {
+			t.Errorf("entry json key %s not found", key)
+		}
+	}
+	if data["level_string"] != "Debug" || data["file"] != "entry_test.go" || data["line"] != float64(30) {
+		t.Errorf("entry json error, got %s", jsonByte)
+	}
+}
+
+func TestEntry_Release(t *testing.T) {
+
+	entry := getEntry()
+	entry.body = "pooled entry"
+	entry.fieldsBuf = appendFields(entry.fieldsBuf[:0], []interface{}{"user", "phachon"})
+	entry.fields = entry.fieldsBuf
+
+	entry.retain()
+	entry.release()
+	if entry.body != "pooled entry" {
+		t.Fatal("entry must not be reset before the last reference is released")
+	}
+	entry.release()
+	if entry.body != "" || entry.fields != nil || len(entry.fieldsBuf) != 0 || cap(entry.fieldsBuf) == 0 {
+		t.Errorf("entry release reset error, got %+v", entry)
+	}
+}
+
+func TestFormatEntry_TokenInBody(t *testing.T) {
+
+	entry := NewEntry(LOGGER_LEVEL_INFO, "100%file% %level%", "rate", "50%")
+	entry.file = "main.go"
+
+	str := FormatEntry("%%%level_string% %body% %file% %unknown%", entry)
+	if str != "%Info 100%file% %level% main.go %unknown% rate=50%" {
+		t.Errorf("FormatEntry must replace tokens of format only, got %s", str)
+	}
+}
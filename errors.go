@@ -0,0 +1,83 @@
+package go_logger
+
+import (
+	"errors"
+	"strconv"
+)
+
+// logger is closed by Close
+var ErrLoggerClosed = errors.New("logger: logger is closed!")
+
+// message is dropped by async policy
+var ErrMessageDropped = errors.New("logger: message dropped by async policy!")
+
+// adapter is not registered
+type UnknownAdapterError struct {
+	Adapter string
+}
+
+func (e *UnknownAdapterError) Error() string {
+	return "logger: adapter " + e.Adapter + " is not registered!"
+}
+
+// encoder is not registered
+type UnknownEncoderError struct {
+	Encoder string
+}
+
+func (e *UnknownEncoderError) Error() string {
+	return "logger: encoder " + e.Encoder + " is not registered!"
+}
+
+// output is already attached
+type DuplicateOutputError struct {
+	Name string
+}
+
+func (e *DuplicateOutputError) Error() string {
+	return "logger: output " + e.Name + " already attached!"
+}
+
+// output is not attached
+type UnknownOutputError struct {
+	Name string
+}
+
+func (e *UnknownOutputError) Error() string {
+	return "logger: output " + e.Name + " is not attached!"
+}
+
+// adapter Init returns error
+type AdapterInitError struct {
+	Adapter string
+	Err     error
+}
+
+func (e *AdapterInitError) Error() string {
+	return "logger: adapter " + e.Adapter + " init failed, error: " + e.Err.Error()
+}
+
+// Unwrap returns the adapter init error
+func (e *AdapterInitError) Unwrap() error {
+	return e.Err
+}
+
+// level is not one of LOGGER_LEVEL_EMERGENCY ... LOGGER_LEVEL_DEBUG
+type IllegalLevelError struct {
+	Level int
+}
+
+func (e *IllegalLevelError) Error() string {
+	return "logger: level " + strconv.Itoa(e.Level) + " is illegal!"
+}
+
+// format string is illegal, see CompileFormat
+type FormatError struct {
+	Format string
+	Pos    int
+	Reason string
+}
+
+func (e *FormatError) Error() string {
+	return "logger: format " + strconv.Quote(e.Format) + " error at " + strconv.Itoa(e.Pos) + ", " + e.Reason + "!"
+}
@@ -0,0 +1,236 @@
+package go_logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// logger message fields map
+// example: logger.Info("login ok", go_logger.Fields{"user": id, "ms": 12})
+type Fields map[string]interface{}
+
+// logger message field
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// entry json keys, a field with the same key or a custom token name is renamed to "fields.key" in json and api output
+var builtinReservedKeys = map[string]bool{
+	"timestamp":          true,
+	"timestamp_format":   true,
+	"millisecond":        true,
+	"millisecond_format": true,
+	"time":               true,
+	"time_unix":          true,
+	"level":              true,
+	"level_string":       true,
+	"body":               true,
+	"file":               true,
+	"line":               true,
+	"function":           true,
+	"path":               true,
+	"rel_path":           true,
+	"package":            true,
+	"short_function":     true,
+	"hostname":           true,
+	"pid":                true,
+	"program":            true,
+	"sequence":           true,
+	"goroutine":          true,
+	"stack":              true,
+}
+
+//make fields from key value pairs
+//params : keyvals "key", value, ... or Fields or Field
+//return : []Field
+func makeFields(keyvals []interface{}) []Field {
+	if len(keyvals) == 0 {
+		return nil
+	}
+	return appendFields(make([]Field, 0, len(keyvals)/2+1), keyvals)
+}
+
+//append fields of key value pairs to dst
+//params : dst []Field, keyvals "key", value, ... or Fields or Field
+//return : []Field
+func appendFields(fields []Field, keyvals []interface{}) []Field {
+	for i := 0; i < len(keyvals); i++ {
+		switch kv := keyvals[i].(type) {
+		case Fields:
+			keys := make([]string, 0, len(kv))
+			for key := range kv {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fields = append(fields, Field{Key: key, Value: kv[key]})
+			}
+		case Field:
+			fields = append(fields, kv)
+		default:
+			field := Field{}
+			if key, ok := kv.(string); ok {
+				field.Key = key
+			} else {
+				field.Key = fmt.Sprint(kv)
+			}
+			if i+1 < len(keyvals) {
+				i++
+				field.Value = keyvals[i]
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+//merge fields with the same key in place, the last value wins at the position of the first key
+//params : fields []Field, modified in place
+//return : []Field
+func mergeFields(fields []Field) []Field {
+	n := 0
+	for _, field := range fields {
+		merged := false
+		for i := 0; i < n; i++ {
+			if fields[i].Key == field.Key {
+				fields[i].Value = field.Value
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			fields[n] = field
+			n++
+		}
+	}
+	for i := n; i < len(fields); i++ {
+		fields[i] = Field{}
+	}
+	return fields[:n]
+}
+
+// field key in json and api output
+func (field Field) outputKey() string {
+	if loadTokens().reserved[field.Key] {
+		return "fields." + field.Key
+	}
+	return field.Key
+}
+
+// field value as string
+func (field Field) String() string {
+	switch value := field.Value.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return value
+	case error:
+		return value.Error()
+	}
+	return fmt.Sprint(field.Value)
+}
+
+//format fields to key=value pairs
+//params : fields
+//return : string
+func fieldsFormat(fields []Field) string {
+	return string(appendFieldsText(nil, fields))
+}
+
+//append fields key=value pairs to dst, value is quoted if empty or has space, '=', '"' or control characters
+//params : dst []byte, fields
+//return : []byte
+func appendFieldsText(dst []byte, fields []Field) []byte {
+	for i, field := range fields {
+		if i > 0 {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, field.Key...)
+		dst = append(dst, '=')
+		start := len(dst)
+		dst = field.appendValue(dst)
+		value := dst[start:]
+		if len(value) == 0 || bytes.ContainsAny(value, " =\"\t\r\n") {
+			dst = strconv.AppendQuote(dst[:start], string(value))
+		}
+	}
+	return dst
+}
+
+//append field value as String to dst
+func (field Field) appendValue(dst []byte) []byte {
+	switch value := field.Value.(type) {
+	case nil:
+		return append(dst, "<nil>"...)
+	case string:
+		return append(dst, value...)
+	case error:
+		return append(dst, value.Error()...)
+	case bool:
+		return strconv.AppendBool(dst, value)
+	case int:
+		return strconv.AppendInt(dst, int64(value), 10)
+	case int32:
+		return strconv.AppendInt(dst, int64(value), 10)
+	case int64:
+		return strconv.AppendInt(dst, value, 10)
+	case uint:
+		return strconv.AppendUint(dst, uint64(value), 10)
+	case uint32:
+		return strconv.AppendUint(dst, uint64(value), 10)
+	case uint64:
+		return strconv.AppendUint(dst, value, 10)
+	case float64:
+		return strconv.AppendFloat(dst, value, 'g', -1, 64)
+	}
+	return append(dst, fmt.Sprint(field.Value)...)
+}
+
+//write fields to json object, a field with a reserved key is renamed to "fields.key"
+//params : out, fields, reserved keys, first is no key written before in the object
+func encodeFieldsJSON(out *jwriter.Writer, fields []Field, reserved map[string]bool, first bool) {
+	for i, field := range fields {
+		if i > 0 || !first {
+			out.RawByte(',')
+		}
+		if reserved[field.Key] {
+			out.String("fields." + field.Key)
+		} else {
+			out.String(field.Key)
+		}
+		out.RawByte(':')
+		switch value := field.Value.(type) {
+		case string:
+			out.String(value)
+		case error:
+			out.String(value.Error())
+		case bool:
+			out.Bool(value)
+		case int:
+			out.Int(value)
+		case int32:
+			out.Int32(value)
+		case int64:
+			out.Int64(value)
+		case uint:
+			out.Uint(value)
+		case uint32:
+			out.Uint32(value)
+		case uint64:
+			out.Uint64(value)
+		default:
+			data, err := json.Marshal(value)
+			if err != nil {
+				out.String(fmt.Sprint(value))
+				continue
+			}
+			out.Raw(data, nil)
+		}
+	}
+}
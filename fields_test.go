@@ -0,0 +1,63 @@
+package go_logger
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMakeFields(t *testing.T) {
+
+	fields := makeFields([]interface{}{"user", 10, Fields{"b": 2, "a": 1}, Field{Key: "c", Value: 3}, "dangling"})
+
+	keys := []string{"user", "a", "b", "c", "dangling"}
+	if len(fields) != len(keys) {
+		t.Fatalf("makeFields length error, got %d", len(fields))
+	}
+	for i, key := range keys {
+		if fields[i].Key != key {
+			t.Errorf("makeFields key error, index %d want %s got %s", i, key, fields[i].Key)
+		}
+	}
+	if fields[4].Value != nil {
+		t.Error("makeFields dangling key value must be nil")
+	}
+	if makeFields(nil) != nil {
+		t.Error("makeFields empty keyvals must be nil")
+	}
+}
+
+func TestFieldsFormat(t *testing.T) {
+
+	fields := makeFields([]interface{}{"user", "phachon", "ms", 12, "msg", "login ok", "err", errors.New("failed")})
+
+	str := fieldsFormat(fields)
+	if str != `user=phachon ms=12 msg="login ok" err=failed` {
+		t.Errorf("fieldsFormat error, got %s", str)
+	}
+}
+
+func TestEntry_MarshalJSONFields(t *testing.T) {
+
+	loggerMsg := &Entry{
+		level:  LOGGER_LEVEL_INFO,
+		body:   "login ok",
+		fields: makeFields([]interface{}{"user", "phachon", "ms", 12, "level", "x"}),
+	}
+	jsonByte, err := loggerMsg.MarshalJSON()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data := map[string]interface{}{}
+	err = json.Unmarshal(jsonByte, &data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if data["user"] != "phachon" || data["ms"] != float64(12) {
+		t.Errorf("marshal fields error, got %s", jsonByte)
+	}
+	if data["level"] != float64(LOGGER_LEVEL_INFO) || data["fields.level"] != "x" {
+		t.Errorf("marshal reserved fields error, got %s", jsonByte)
+	}
+}
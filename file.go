@@ -1,6 +1,7 @@
 package go_logger
 
 import (
+	"bytes"
 	"errors"
 	"github.com/phachon/go-logger/utils"
 	"os"
@@ -27,8 +28,9 @@ const (
 
 // adapter file
 type AdapterFile struct {
-	write  map[int]*FileWriter
-	config *FileConfig
+	write   map[int]*FileWriter
+	config  *FileConfig
+	encoder Encoder
 }
 
 // file writer
@@ -68,10 +70,14 @@ type FileConfig struct {
 	// "h" Log files are cut through hour
 	DateSlice string
 
+	// output encoder name, "text", "json", "logfmt", "ecs", "gelf" or a name of RegisterEncoder
+	// if encoder is empty, "json" if JsonFormat is true, otherwise "text"
+	Encoder string
+
 	// is json format
 	JsonFormat bool
 
-	// jsonFormat is false, please input format string
+	// text encoder format string
 	// if format is empty, default format "%millisecond_format% [%level_string%] %body%"
 	//
 	//  Timestamp "%timestamp%"
@@ -84,9 +90,31 @@ type FileConfig struct {
 	//	File string "%file%"
 	//	Line int "%line%"
 	//	Function "%function%"
+	//	Time "%time%", TimeUnix "%time_unix%", see TimeLayout, TimeZone, TimePrecision
+	//	Path "%path%", RelativePath "%rel_path%", Package "%package%", ShortFunction "%short_function%" of the caller
+	//	Hostname "%hostname%", Pid "%pid%", Program "%program%"
+	//	Sequence "%sequence%", Goroutine "%goroutine%", see Logger.SetGoroutineId
+	//	Stack "%stack%", empty if not captured, see Logger.SetStackLevel
+	//	custom tokens of RegisterToken
+	//	Fields "%fields%", if not in format, fields key=value pairs are appended to the end
+	//
+	// format is compiled at Init, unknown tokens are an error, see CompileFormat for "%%" and width modifiers
 	//
 	// example: format = "%millisecond_format% [%level_string%] %body%"
 	Format string
+
+	// %time% token and time json key layout, default time.RFC3339Nano
+	TimeLayout string
+
+	// %time% token and time json key time zone, "UTC", "Local" or IANA name as "Asia/Shanghai", default "Local"
+	TimeZone string
+
+	// %time% precision and %time_unix% unit, "s", "ms", "us", "ns", default "ms"
+	TimePrecision string
+
+	// json encoder keys renamed, selected, omitted, nested and constant keys, nil is the keys of Entry.MarshalJSON
+	// if JsonSchema is set and Encoder is empty, Encoder is "json"
+	JsonSchema *JsonSchema
 }
 
 func (fc *FileConfig) Name() string {
@@ -94,6 +122,7 @@ func (fc *FileConfig) Name() string {
 }
 
 var fileSliceDateMapping = map[string]int{
+	FILE_SLICE_DATE_NULL:  -1,
 	FILE_SLICE_DATE_YEAR:  0,
 	FILE_SLICE_DATE_MONTH: 1,
 	FILE_SLICE_DATE_DAY:   2,
@@ -117,9 +146,23 @@ func (adapterFile *AdapterFile) Init(fileConfig Config) error {
 	fc := vc.Interface().(*FileConfig)
 	adapterFile.config = fc
 
-	if fc.JsonFormat == false && fc.Format == "" {
+	if fc.Encoder == "" && fc.JsonSchema != nil {
+		fc.Encoder = ENCODER_JSON
+	}
+	if fc.Encoder == "" && fc.JsonFormat == false && fc.Format == "" {
 		fc.Format = defaultLoggerMessageFormat
 	}
+	encoder, err := newEncoder(fc.Encoder, fc.JsonFormat, &EncoderConfig{
+		Format:        fc.Format,
+		TimeLayout:    fc.TimeLayout,
+		TimeZone:      fc.TimeZone,
+		TimePrecision: fc.TimePrecision,
+		JsonSchema:    fc.JsonSchema,
+	})
+	if err != nil {
+		return err
+	}
+	adapterFile.encoder = encoder
 
 	if len(adapterFile.config.LevelFileName) == 0 {
 		if adapterFile.config.Filename == "" {
@@ -128,95 +171,118 @@ func (adapterFile *AdapterFile) Init(fileConfig Config) error {
 	}
 	_, ok := fileSliceDateMapping[adapterFile.config.DateSlice]
 	if !ok {
-		return errors.New("config DateSlice must be one of the 'y', 'd', 'm','h'!")
+		return errors.New("config DateSlice must be one of the '', 'y', 'd', 'm','h'!")
 	}
 
-	// init FileWriter
+	// init FileWriter, the files already opened are closed on error
 	if len(adapterFile.config.LevelFileName) > 0 {
 		fileWriters := map[int]*FileWriter{}
+		adapterFile.write = fileWriters
 		for level, filename := range adapterFile.config.LevelFileName {
 			_, ok := levelStringMapping[level]
 			if !ok {
+				adapterFile.Close()
 				return errors.New("config LevelFileName key level is illegal!")
 			}
 			fw := NewFileWrite(filename)
-			fw.initFile()
 			fileWriters[level] = fw
+			err = fw.initFile()
+			if err != nil {
+				adapterFile.Close()
+				return err
+			}
 		}
-		adapterFile.write = fileWriters
 	}
 
 	if adapterFile.config.Filename != "" {
 		fw := NewFileWrite(adapterFile.config.Filename)
-		fw.initFile()
 		adapterFile.write[FILE_ACCESS_LEVEL] = fw
+		err = fw.initFile()
+		if err != nil {
+			adapterFile.Close()
+			return err
+		}
 	}
 
 	return nil
 }
 
-// Write
-func (adapterFile *AdapterFile) Write(loggerMsg *loggerMessage) error {
+// Write, the access file and the level file are written in order
+func (adapterFile *AdapterFile) Write(loggerMsg *Entry) error {
 
-	var accessChan = make(chan error, 1)
-	var levelChan = make(chan error, 1)
+	var accessErr error
+	var levelErr error
 
 	// access file write
 	if adapterFile.config.Filename != "" {
-		go func() {
-			accessFileWrite, ok := adapterFile.write[FILE_ACCESS_LEVEL]
-			if !ok {
-				accessChan <- nil
-				return
-			}
-			err := accessFileWrite.writeByConfig(adapterFile.config, loggerMsg)
-			if err != nil {
-				accessChan <- err
-				return
-			}
-			accessChan <- nil
-		}()
+		accessFileWrite, ok := adapterFile.write[FILE_ACCESS_LEVEL]
+		if ok {
+			accessErr = accessFileWrite.writeByConfig(adapterFile.config, adapterFile.encoder, loggerMsg)
+		}
 	}
 
 	// level file write
 	if len(adapterFile.config.LevelFileName) != 0 {
-		go func() {
-			fileWrite, ok := adapterFile.write[loggerMsg.Level]
-			if !ok {
-				levelChan <- nil
-				return
-			}
-			err := fileWrite.writeByConfig(adapterFile.config, loggerMsg)
-			if err != nil {
-				levelChan <- err
-				return
-			}
-			levelChan <- nil
-		}()
+		fileWrite, ok := adapterFile.write[loggerMsg.level]
+		if ok {
+			levelErr = fileWrite.writeByConfig(adapterFile.config, adapterFile.encoder, loggerMsg)
+		}
 	}
 
-	var accessErr error
-	var levelErr error
+	if accessErr != nil {
+		return accessErr
+	}
+	return levelErr
+}
+
+// WriteBatch, messages of a file are written by one write
+func (adapterFile *AdapterFile) WriteBatch(entries []*Entry) error {
+
+	var writeErr error
+
+	// access file write
 	if adapterFile.config.Filename != "" {
-		accessErr = <-accessChan
+		accessFileWrite, ok := adapterFile.write[FILE_ACCESS_LEVEL]
+		if ok {
+			writeErr = accessFileWrite.writeBatchByConfig(adapterFile.config, adapterFile.encoder, entries)
+		}
 	}
+
+	// level file write
 	if len(adapterFile.config.LevelFileName) != 0 {
-		levelErr = <-levelChan
-	}
-	if accessErr != nil {
-		return accessErr.(error)
-	}
-	if levelErr != nil {
-		return levelErr.(error)
+		levelEntries := map[int][]*Entry{}
+		for _, loggerMsg := range entries {
+			if _, ok := adapterFile.write[loggerMsg.level]; ok {
+				levelEntries[loggerMsg.level] = append(levelEntries[loggerMsg.level], loggerMsg)
+			}
+		}
+		for level, fileEntries := range levelEntries {
+			err := adapterFile.write[level].writeBatchByConfig(adapterFile.config, adapterFile.encoder, fileEntries)
+			if err != nil && writeErr == nil {
+				writeErr = err
+			}
+		}
 	}
-	return nil
+	return writeErr
 }
 
-// Flush
+// Flush, sync file data to disk, file is usable after Flush
 func (adapterFile *AdapterFile) Flush() {
 	for _, fileWrite := range adapterFile.write {
-		fileWrite.writer.Close()
+		fileWrite.sync()
+	}
+}
+
+// Close, close all files
+func (adapterFile *AdapterFile) Close() error {
+	var closeErr error
+	for _, fileWrite := range adapterFile.write {
+		err := fileWrite.close()
+		if err != nil && closeErr == nil {
+			closeErr = err
+		}
 	}
+	return closeErr
 }
 
 // Name
@@ -255,12 +321,45 @@ func (fw *FileWriter) initFile() error {
 	return nil
 }
 
-// write by config
-func (fw *FileWriter) writeByConfig(config *FileConfig, loggerMsg *loggerMessage) error {
+// sync file data to disk
+func (fw *FileWriter) sync() error {
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	if fw.writer == nil {
+		return nil
+	}
+	return fw.writer.Sync()
+}
+
+// close file
+func (fw *FileWriter) close() error {
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	if fw.writer == nil {
+		return nil
+	}
+	err := fw.writer.Close()
+	fw.writer = nil
+	return err
+}
+
+// write by config, encoder is the entry encoder of config
+func (fw *FileWriter) writeByConfig(config *FileConfig, encoder Encoder, loggerMsg *Entry) error {
+	return fw.writeBatchByConfig(config, encoder, []*Entry{loggerMsg})
+}
+
+// write a batch of messages by config, file is sliced before the batch and written by one write
+func (fw *FileWriter) writeBatchByConfig(config *FileConfig, encoder Encoder, entries []*Entry) error {
 
 	fw.lock.Lock()
 	defer fw.lock.Unlock()
 
+	if fw.writer == nil {
+		return errors.New("file " + fw.filename + " is closed!")
+	}
+
 	if config.DateSlice != "" {
 		// file slice by date
 		err := fw.sliceByDate(config.DateSlice)
@@ -283,22 +382,16 @@ func (fw *FileWriter) writeByConfig(config *FileConfig, loggerMsg *loggerMessage
 		}
 	}
 
-	msg := ""
-	if config.JsonFormat == true {
-		//jsonByte, _ := json.Marshal(loggerMsg)
-		jsonByte, _ := loggerMsg.MarshalJSON()
-		msg = string(jsonByte) + "\r\n"
-	} else {
-		msg = loggerMessageFormat(config.Format, loggerMsg) + "\r\n"
+	buf := getBuffer()
+	defer putBuffer(buf)
+	for _, loggerMsg := range entries {
+		buf.bs = encoder.Encode(buf.bs, loggerMsg)
+		buf.bs = append(buf.bs, "\r\n"...)
 	}
 
-	fw.writer.Write([]byte(msg))
+	fw.writer.Write(buf.bs)
 	if config.MaxLine != 0 {
-		if config.JsonFormat == true {
-			fw.startLine += 1
-		} else {
-			fw.startLine += int64(strings.Count(msg, "\n"))
-		}
+		fw.startLine += int64(bytes.Count(buf.bs, []byte("\n")))
 	}
 	return nil
 }
@@ -1,6 +1,10 @@
 package go_logger
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -17,12 +21,25 @@ func TestAdapterFile_Name(t *testing.T) {
 	}
 }
 
+// temp dir of the test log files, removed by the returned func
+func testTempDir(tb testing.TB) (string, func()) {
+	dir, err := ioutil.TempDir("", "go-logger")
+	if err != nil {
+		tb.Fatal(err.Error())
+	}
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
 func TestAdapterFile_Write(t *testing.T) {
 
+	dir, remove := testTempDir(t)
+	defer remove()
 	fileAdapter := NewAdapterFile()
 
 	fileConfig := &FileConfig{
-		Filename:      "./test.log",
+		Filename:      filepath.Join(dir, "test.log"),
 		LevelFileName: map[int]string{},
 		MaxLine:       2000,
 		MaxSize:       10000 * 4,
@@ -34,17 +51,13 @@ func TestAdapterFile_Write(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	loggerMsg := &loggerMessage{
-		Timestamp:         time.Now().Unix(),
-		TimestampFormat:   time.Now().Format("2006-01-02 15:04:05"),
-		Millisecond:       time.Now().UnixNano() / 1e6,
-		MillisecondFormat: time.Now().Format("2006-01-02 15:04:05.999"),
-		Level:             LOGGER_LEVEL_DEBUG,
-		LevelString:       "debug",
-		Body:              "logger test file adapter write",
-		File:              "file_test.go",
-		Line:              50,
-		Function:          "TestAdapterFile_Write",
+	loggerMsg := &Entry{
+		time:     time.Now(),
+		level:    LOGGER_LEVEL_DEBUG,
+		body:     "logger test file adapter write",
+		file:     "file_test.go",
+		line:     50,
+		function: "TestAdapterFile_Write",
 	}
 	err = fileAdapter.Write(loggerMsg)
 	if err != nil {
@@ -54,14 +67,16 @@ func TestAdapterFile_Write(t *testing.T) {
 
 func TestAdapterFile_WriteLevelFile(t *testing.T) {
 
+	dir, remove := testTempDir(t)
+	defer remove()
 	fileAdapter := NewAdapterFile()
 
 	fileConfig := &FileConfig{
-		Filename: "./test.log",
+		Filename: filepath.Join(dir, "test.log"),
 		LevelFileName: map[int]string{
-			LOGGER_LEVEL_DEBUG: "./debug.log",
-			LOGGER_LEVEL_INFO:  "./info.log",
-			LOGGER_LEVEL_ERROR: "./error.log",
+			LOGGER_LEVEL_DEBUG: filepath.Join(dir, "debug.log"),
+			LOGGER_LEVEL_INFO:  filepath.Join(dir, "info.log"),
+			LOGGER_LEVEL_ERROR: filepath.Join(dir, "error.log"),
 		},
 		MaxLine:    2000,
 		MaxSize:    10000 * 4,
@@ -73,21 +88,60 @@ func TestAdapterFile_WriteLevelFile(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	loggerMsg := &loggerMessage{
-		Timestamp:         time.Now().Unix(),
-		TimestampFormat:   time.Now().Format("2006-01-02 15:04:05"),
-		Millisecond:       time.Now().UnixNano() / 1e6,
-		MillisecondFormat: time.Now().Format("2006-01-02 15:04:05.999"),
-		Level:             LOGGER_LEVEL_DEBUG,
-		LevelString:       "debug",
-		Body:              "logger test file adapter write",
-		File:              "file_test.go",
-		Line:              50,
-		Function:          "TestAdapterFile_Write",
+	loggerMsg := &Entry{
+		time:     time.Now(),
+		level:    LOGGER_LEVEL_DEBUG,
+		body:     "logger test file adapter write",
+		file:     "file_test.go",
+		line:     50,
+		function: "TestAdapterFile_Write",
 	}
 	fileAdapter.Write(loggerMsg)
-	loggerMsg.Level = LOGGER_LEVEL_INFO
+	loggerMsg.level = LOGGER_LEVEL_INFO
 	fileAdapter.Write(loggerMsg)
-	loggerMsg.Level = LOGGER_LEVEL_ERROR
+	loggerMsg.level = LOGGER_LEVEL_ERROR
 	fileAdapter.Write(loggerMsg)
 }
+
+func TestAdapterFile_WriteBatch(t *testing.T) {
+
+	os.Remove("./batch.log")
+	os.Remove("./batch_error.log")
+	defer os.Remove("./batch.log")
+	defer os.Remove("./batch_error.log")
+
+	fileAdapter := NewAdapterFile()
+	err := fileAdapter.Init(&FileConfig{
+		Filename: "./batch.log",
+		LevelFileName: map[int]string{
+			LOGGER_LEVEL_ERROR: "./batch_error.log",
+		},
+		MaxLine: 2000,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = fileAdapter.(LoggerBatchWriter).WriteBatch([]*Entry{
+		NewEntry(LOGGER_LEVEL_INFO, "batch message 1"),
+		NewEntry(LOGGER_LEVEL_ERROR, "batch message 2"),
+		NewEntry(LOGGER_LEVEL_INFO, "batch message 3"),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	fileAdapter.Flush()
+
+	content, _ := ioutil.ReadFile("./batch.log")
+	if strings.Count(string(content), "batch message") != 3 {
+		t.Errorf("file adapter write batch error, got %s", content)
+	}
+	content, _ = ioutil.ReadFile("./batch_error.log")
+	if strings.Count(string(content), "batch message") != 1 {
+		t.Errorf("file adapter write batch level file error, got %s", content)
+	}
+	if fileAdapter.(*AdapterFile).write[FILE_ACCESS_LEVEL].startLine != 4 {
+		t.Errorf("file adapter write batch line error, got %d", fileAdapter.(*AdapterFile).write[FILE_ACCESS_LEVEL].startLine)
+	}
+	fileAdapter.(*AdapterFile).Close()
+}
@@ -0,0 +1,352 @@
+package go_logger
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// append a token value of entry to dst, time tokens are formatted by the output time encoder
+type tokenAppender func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte
+
+// built-in format tokens, "%name%" in Format is replaced by the token value
+var builtinFormatTokens = map[string]tokenAppender{
+	"timestamp": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendInt(dst, entry.timestamp(), 10)
+	},
+	"timestamp_format": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return entry.appendTimestampFormat(dst)
+	},
+	"millisecond": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendInt(dst, entry.millisecond(), 10)
+	},
+	"millisecond_format": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return entry.appendMillisecondFormat(dst)
+	},
+	"time": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return timeEncoder.appendTime(dst, entry.time)
+	},
+	"time_unix": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendInt(dst, timeEncoder.unix(entry.time), 10)
+	},
+	"level": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendInt(dst, int64(entry.level), 10)
+	},
+	"level_string": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, entry.LevelString()...)
+	},
+	"body": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, entry.body...)
+	},
+	"file": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, entry.file...)
+	},
+	"line": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendInt(dst, int64(entry.line), 10)
+	},
+	"function": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, entry.function...)
+	},
+	"path": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, entry.path...)
+	},
+	"rel_path": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return appendRelativePath(dst, entry.path, entry.function)
+	},
+	"package": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, functionPackage(entry.function)...)
+	},
+	"short_function": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, shortFunction(entry.function)...)
+	},
+	"hostname": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, processHostname...)
+	},
+	"pid": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendInt(dst, int64(processPid), 10)
+	},
+	"program": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, processProgram...)
+	},
+	"sequence": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendUint(dst, entry.sequence, 10)
+	},
+	"goroutine": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return strconv.AppendUint(dst, entry.goroutine, 10)
+	},
+	"stack": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, entry.stack...)
+	},
+	"fields": func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return appendFieldsText(dst, entry.fields)
+	},
+}
+
+// custom token registered by RegisterToken
+type customToken struct {
+	name  string
+	value func(entry *Entry) string
+}
+
+// registered tokens snapshot, must not be modified
+type tokenRegistry struct {
+	appenders map[string]tokenAppender // format tokens by name
+	custom    []customToken            // custom tokens in registration order, written as json keys and api params
+	reserved  map[string]bool          // entry json keys and custom token names
+}
+
+var (
+	tokensLock sync.Mutex   // lock of RegisterToken
+	tokens     atomic.Value // *tokenRegistry snapshot, replaced by a copy under tokensLock, loaded without lock
+)
+
+func init() {
+	tokens.Store(&tokenRegistry{appenders: builtinFormatTokens, reserved: builtinReservedKeys})
+}
+
+//load registered tokens snapshot
+//return : *tokenRegistry
+func loadTokens() *tokenRegistry {
+	return tokens.Load().(*tokenRegistry)
+}
+
+//Register custom format token, "%name%" in format is replaced by value(entry)
+//custom tokens are written as json keys and api params too, a field with the same key is renamed to "fields.name"
+//tokens must be registered before adapters Init, usually in init, formats compiled before do not know the token
+//RegisterToken is safe to call concurrently with logging, the registered tokens are replaced by a copy
+//params : name, letters, digits and '_'; value func(entry *Entry) string
+func RegisterToken(name string, value func(entry *Entry) string) {
+	if name == "" || strings.IndexFunc(name, illegalTokenRune) >= 0 {
+		panic("logger: format token " + name + " is illegal!")
+	}
+	if value == nil {
+		panic("logger: format token " + name + " is nil!")
+	}
+
+	tokensLock.Lock()
+	defer tokensLock.Unlock()
+	old := loadTokens()
+	if old.appenders[name] != nil {
+		panic("logger: format token " + name + " already registered!")
+	}
+
+	registry := &tokenRegistry{
+		appenders: make(map[string]tokenAppender, len(old.appenders)+1),
+		custom:    append(old.custom[:len(old.custom):len(old.custom)], customToken{name: name, value: value}),
+		reserved:  make(map[string]bool, len(old.reserved)+1),
+	}
+	for key, appender := range old.appenders {
+		registry.appenders[key] = appender
+	}
+	registry.appenders[name] = func(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+		return append(dst, value(entry)...)
+	}
+	for key := range old.reserved {
+		registry.reserved[key] = true
+	}
+	registry.reserved[name] = true
+	tokens.Store(registry)
+}
+
+//token name is letters, digits and '_'
+func illegalTokenRune(r rune) bool {
+	return !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+}
+
+// format compiled to segments once, see CompileFormat
+type FormatTemplate struct {
+	segments    []formatSegment
+	hasFields   bool
+	timeEncoder *timeEncoder
+}
+
+// literal text or a token of the format
+type formatSegment struct {
+	literal string
+	name    string // token name, empty is literal
+	token   tokenAppender
+	width   int // min width in runes, < 0 is left aligned, 0 is not padded
+	max     int // max width in runes, longer value is truncated, 0 is not truncated
+}
+
+// compile format to a template
+//
+// token: "%name%" or "%name:modifier%", modifier is [-]width[.max] as printf %-8.8s
+//
+//	"%level_string:-8%" left aligned, padded to 8 runes
+//	"%function:30%" right aligned, padded to 30 runes
+//	"%function:.30%" truncated to 30 runes
+//
+// "%%" is a literal '%', a token may be used more than once
+// "%time%" is RFC3339Nano in local time with millisecond precision, adapters set it by the config TimeLayout, TimeZone, TimePrecision
+// params : format string
+// return : *FormatTemplate, error *FormatError if a token is unknown, a modifier is illegal or '%' is not closed
+func CompileFormat(format string) (*FormatTemplate, error) {
+	return compileFormat(format, true)
+}
+
+// compile format, if not strict, unknown tokens and illegal modifiers are written as literal text
+func compileFormat(format string, strict bool) (*FormatTemplate, error) {
+	template := &FormatTemplate{timeEncoder: defaultTimeEncoder}
+	literal := []byte{}
+	for i := 0; i < len(format); {
+		j := strings.IndexByte(format[i:], '%')
+		if j < 0 {
+			literal = append(literal, format[i:]...)
+			break
+		}
+		literal = append(literal, format[i:i+j]...)
+		i += j
+
+		if strings.HasPrefix(format[i:], "%%") {
+			literal = append(literal, '%')
+			i += 2
+			continue
+		}
+		end := strings.IndexByte(format[i+1:], '%')
+		if end < 0 {
+			if strict {
+				return nil, &FormatError{Format: format, Pos: i, Reason: "'%' is not closed"}
+			}
+			literal = append(literal, format[i:]...)
+			break
+		}
+		segment, err := compileToken(format[i+1 : i+1+end])
+		if err != nil {
+			if strict {
+				return nil, &FormatError{Format: format, Pos: i, Reason: err.Error()}
+			}
+			// not a token, the closing % may start a token
+			literal = append(literal, '%')
+			i++
+			continue
+		}
+
+		if len(literal) > 0 {
+			template.segments = append(template.segments, formatSegment{literal: string(literal)})
+			literal = literal[:0]
+		}
+		template.segments = append(template.segments, segment)
+		if segment.name == "fields" {
+			template.hasFields = true
+		}
+		i += end + 2
+	}
+	if len(literal) > 0 {
+		template.segments = append(template.segments, formatSegment{literal: string(literal)})
+	}
+	return template, nil
+}
+
+// compile "name" or "name:modifier" of a token
+func compileToken(token string) (formatSegment, error) {
+	name := token
+	modifier := ""
+	if k := strings.IndexByte(token, ':'); k >= 0 {
+		name = token[:k]
+		modifier = token[k+1:]
+	}
+	appender, ok := loadTokens().appenders[name]
+	if !ok {
+		return formatSegment{}, errors.New("unknown token '" + name + "'")
+	}
+	segment := formatSegment{name: name, token: appender}
+	if modifier == "" {
+		return segment, nil
+	}
+
+	width := modifier
+	max := ""
+	if k := strings.IndexByte(modifier, '.'); k >= 0 {
+		width = modifier[:k]
+		max = modifier[k+1:]
+		if max == "" {
+			return formatSegment{}, errors.New("illegal modifier '" + modifier + "' of token '" + name + "'")
+		}
+	}
+	if width != "" {
+		n, err := strconv.Atoi(width)
+		if err != nil || n == 0 || strings.HasPrefix(width, "+") {
+			return formatSegment{}, errors.New("illegal modifier '" + modifier + "' of token '" + name + "'")
+		}
+		segment.width = n
+	}
+	if max != "" {
+		n, err := strconv.Atoi(max)
+		if err != nil || n <= 0 || strings.HasPrefix(max, "+") {
+			return formatSegment{}, errors.New("illegal modifier '" + modifier + "' of token '" + name + "'")
+		}
+		segment.max = n
+	}
+	return segment, nil
+}
+
+// format entry to string
+func (template *FormatTemplate) Format(entry *Entry) string {
+	return string(template.AppendFormat(nil, entry))
+}
+
+// append formatted entry to dst, fields key=value pairs are appended to the end if "%fields%" is not in format
+// params : dst []byte, entry
+// return : []byte
+func (template *FormatTemplate) AppendFormat(dst []byte, entry *Entry) []byte {
+	for i := range template.segments {
+		segment := &template.segments[i]
+		if segment.token == nil {
+			dst = append(dst, segment.literal...)
+			continue
+		}
+		if segment.width == 0 && segment.max == 0 {
+			dst = segment.token(dst, entry, template.timeEncoder)
+			continue
+		}
+		dst = segment.appendModified(dst, entry, template.timeEncoder)
+	}
+	if !template.hasFields && len(entry.fields) > 0 {
+		dst = append(dst, ' ')
+		dst = appendFieldsText(dst, entry.fields)
+	}
+	return dst
+}
+
+// append token value truncated and padded by modifier
+func (segment *formatSegment) appendModified(dst []byte, entry *Entry, timeEncoder *timeEncoder) []byte {
+	start := len(dst)
+	dst = segment.token(dst, entry, timeEncoder)
+	runes := utf8.RuneCount(dst[start:])
+
+	if segment.max > 0 && runes > segment.max {
+		end := start
+		for n := 0; n < segment.max; n++ {
+			_, size := utf8.DecodeRune(dst[end:])
+			end += size
+		}
+		dst = dst[:end]
+		runes = segment.max
+	}
+
+	width := segment.width
+	left := width < 0
+	if left {
+		width = -width
+	}
+	if runes >= width {
+		return dst
+	}
+	pad := width - runes
+	for n := 0; n < pad; n++ {
+		dst = append(dst, ' ')
+	}
+	if !left {
+		// right aligned, move the value after the padding
+		end := len(dst) - pad
+		copy(dst[start+pad:], dst[start:end])
+		for n := start; n < start+pad; n++ {
+			dst[n] = ' '
+		}
+	}
+	return dst
+}
@@ -0,0 +1,142 @@
+package go_logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"testing"
+)
+
+func TestCompileFormat(t *testing.T) {
+
+	entry := NewEntry(LOGGER_LEVEL_INFO, "login ok", "user", "phachon")
+	entry.function = "main.main"
+	entry.file = "main.go"
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"[%level_string:-8%] %body%", "[Info    ] login ok user=phachon"},
+		{"[%level_string:8%] %body% %fields%", "[    Info] login ok user=phachon"},
+		{"%function:.4% %body%", "main login ok user=phachon"},
+		{"%function:-6.4%|%file:3%", "main  |main.go user=phachon"},
+		{"%body% %body% 100%%", "login ok login ok 100% user=phachon"},
+	}
+	for _, test := range tests {
+		template, err := CompileFormat(test.format)
+		if err != nil {
+			t.Errorf("compile format %s error, %s", test.format, err.Error())
+			continue
+		}
+		str := template.Format(entry)
+		if str != test.want {
+			t.Errorf("format %s error, want %s got %s", test.format, test.want, str)
+		}
+	}
+}
+
+func TestCompileFormatError(t *testing.T) {
+
+	formats := []string{
+		"%level_strin% %body%",
+		"%body",
+		"%level_string:abc%",
+		"%level_string:0%",
+		"%level_string:8.%",
+		"%level_string:.-1%",
+		"100% done",
+	}
+	for _, format := range formats {
+		_, err := CompileFormat(format)
+		if _, ok := err.(*FormatError); !ok {
+			t.Errorf("compile format %s must return *FormatError, got %v", format, err)
+		}
+	}
+}
+
+func TestFormatTemplate_Unicode(t *testing.T) {
+
+	template, err := CompileFormat("%body:-6.4%|")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	str := template.Format(NewEntry(LOGGER_LEVEL_INFO, "日志消息测试"))
+	if str != "日志消息  |" {
+		t.Errorf("format unicode width error, got %s", str)
+	}
+}
+
+func init() {
+	RegisterToken("test_region", func(entry *Entry) string {
+		return "eu-" + entry.LevelString()
+	})
+}
+
+func TestRegisterToken(t *testing.T) {
+
+	template, err := CompileFormat("[%test_region:-12%] %body%")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	str := template.Format(NewEntry(LOGGER_LEVEL_INFO, "custom token", "test_region", "field"))
+	if str != "[eu-Info     ] custom token test_region=field" {
+		t.Errorf("custom token format error, got %s", str)
+	}
+
+	jsonByte, _ := NewEntry(LOGGER_LEVEL_ERROR, "custom token", "test_region", "field").MarshalJSON()
+	data := map[string]interface{}{}
+	json.Unmarshal(jsonByte, &data)
+	if data["test_region"] != "eu-Error" || data["fields.test_region"] != "field" {
+		t.Errorf("custom token json error, got %s", jsonByte)
+	}
+}
+
+func TestRegisterTokenPanic(t *testing.T) {
+
+	names := []string{"body", "test_region", "", "bad:name", "bad%name"}
+	for _, name := range names {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("register token %q must panic", name)
+				}
+			}()
+			RegisterToken(name, func(entry *Entry) string { return "" })
+		}()
+	}
+}
+
+func TestRegisterTokenConcurrent(t *testing.T) {
+
+	// tokens registered by the test are removed after the test
+	defer tokens.Store(loadTokens())
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{JsonFormat: true})
+	logger.Output("console").(*AdapterConsole).write.writer = ioutil.Discard
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			RegisterToken("test_concurrent_"+strconv.Itoa(i), func(entry *Entry) string {
+				return entry.LevelString()
+			})
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		logger.Info("concurrent token", "test_concurrent_1", i)
+		CompileFormat("%test_concurrent_1% %body%")
+	}
+	<-done
+
+	template, err := CompileFormat("%test_concurrent_19% %body%")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if str := template.Format(NewEntry(LOGGER_LEVEL_INFO, "registered")); str != "Info registered" {
+		t.Errorf("concurrent token format error, got %s", str)
+	}
+}
@@ -0,0 +1,164 @@
+package go_logger
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/mailru/easyjson/buffer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// GELF additional fields of the entry, a field with the same key is renamed to "_fields.key"
+var gelfReservedKeys = map[string]bool{
+	"id":        true,
+	"file":      true,
+	"line":      true,
+	"function":  true,
+	"pid":       true,
+	"program":   true,
+	"goroutine": true,
+}
+
+// GELF 1.1 encoder, json of a Graylog message
+//
+//	{"version":"1.1","host":"web-1","short_message":"login ok","timestamp":1521816907.003,"level":6,
+//	"_file":"main.go","_line":12,"_function":"main.main","_pid":1024,"_program":"app","_user":"phachon"}
+//
+// level is the syslog severity of the logger level, stack is "full_message" if captured,
+// goroutine is "_goroutine" if captured, custom tokens and fields are additional fields "_key",
+// illegal characters of a key are replaced by '_', values other than numbers are strings,
+// timestamp has the decimals of the config TimePrecision
+//
+// Graylog GELF HTTP input receives one message of a request, use the api adapter without async batches
+type gelfEncoder struct {
+	timeEncoder *timeEncoder
+	reserved    map[string]bool
+}
+
+func newGELFEncoder(config *EncoderConfig) (Encoder, error) {
+	timeEncoder, err := newTimeEncoder(config.TimeLayout, config.TimeZone, config.TimePrecision)
+	if err != nil {
+		return nil, err
+	}
+	reserved := map[string]bool{}
+	for key := range gelfReservedKeys {
+		reserved[key] = true
+	}
+	for _, token := range loadTokens().custom {
+		reserved[token.name] = true
+	}
+	return &gelfEncoder{timeEncoder: timeEncoder, reserved: reserved}, nil
+}
+
+func (encoder *gelfEncoder) Encode(dst []byte, entry *Entry) []byte {
+	out := jwriter.Writer{Buffer: buffer.Buffer{Buf: dst}}
+	out.RawString(`{"version":"1.1","host":`)
+	out.String(processHostname)
+	out.RawString(`,"short_message":`)
+	out.String(entry.body)
+	if entry.stack != "" {
+		out.RawString(`,"full_message":`)
+		out.String(entry.stack)
+	}
+	out.RawString(`,"timestamp":`)
+	out.Buffer.Buf = appendGELFTimestamp(out.Buffer.Buf, entry.time, encoder.timeEncoder.precision)
+	out.RawString(`,"level":`)
+	out.Int(entry.level)
+	out.RawString(`,"_file":`)
+	out.String(entry.file)
+	out.RawString(`,"_line":`)
+	out.Int(entry.line)
+	out.RawString(`,"_function":`)
+	out.String(entry.function)
+	out.RawString(`,"_pid":`)
+	out.Int(processPid)
+	out.RawString(`,"_program":`)
+	out.String(processProgram)
+	if entry.goroutine != 0 {
+		out.RawString(`,"_goroutine":`)
+		out.Uint64(entry.goroutine)
+	}
+	for _, token := range loadTokens().custom {
+		out.RawByte(',')
+		appendGELFKey(&out, token.name)
+		out.String(token.value(entry))
+	}
+	for _, field := range entry.fields {
+		out.RawByte(',')
+		if encoder.reserved[field.Key] {
+			appendGELFKey(&out, "fields."+field.Key)
+		} else {
+			appendGELFKey(&out, field.Key)
+		}
+		encodeGELFValue(&out, field)
+	}
+	out.RawByte('}')
+	return out.Buffer.BuildBytes()
+}
+
+func (encoder *gelfEncoder) ContentType() string {
+	return "application/json"
+}
+
+// append unix seconds with the decimals of precision, 1521816907.003 of millisecond
+func appendGELFTimestamp(dst []byte, t time.Time, precision time.Duration) []byte {
+	dst = strconv.AppendInt(dst, t.Unix(), 10)
+	digits := 0
+	for unit := precision; unit < time.Second; unit *= 10 {
+		digits++
+	}
+	if digits == 0 {
+		return dst
+	}
+	// nanoseconds with the leading 1 of 1e9 keep the zeros, truncated to digits
+	buf := [10]byte{}
+	fraction := strconv.AppendInt(buf[:0], int64(t.Nanosecond())+1e9, 10)
+	dst = append(dst, '.')
+	return append(dst, fraction[1:1+digits]...)
+}
+
+// write additional field key `"_key":`, characters other than letters, digits, '_', '.' and '-' are replaced by '_'
+func appendGELFKey(out *jwriter.Writer, key string) {
+	out.RawString(`"_`)
+	for _, r := range key {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			out.RawByte(byte(r))
+			continue
+		}
+		out.RawByte('_')
+	}
+	out.RawString(`":`)
+}
+
+// write additional field value, GELF values are numbers or strings
+func encodeGELFValue(out *jwriter.Writer, field Field) {
+	switch value := field.Value.(type) {
+	case int:
+		out.Int(value)
+	case int32:
+		out.Int32(value)
+	case int64:
+		out.Int64(value)
+	case uint:
+		out.Uint(value)
+	case uint32:
+		out.Uint32(value)
+	case uint64:
+		out.Uint64(value)
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			out.String(strconv.FormatFloat(value, 'g', -1, 64))
+			return
+		}
+		out.Float64(value)
+	case string:
+		out.String(value)
+	default:
+		out.String(field.String())
+	}
+}
+
+func init() {
+	RegisterEncoder(ENCODER_GELF, newGELFEncoder)
+}
@@ -0,0 +1,54 @@
+package go_logger
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGELFEncoder_Encode(t *testing.T) {
+
+	encoder, err := newEncoder(ENCODER_GELF, false, &EncoderConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	entry := NewEntry(LOGGER_LEVEL_ERROR, "login failed", "user id", "phachon", "id", 7, "ok", false, "err", errors.New("timeout"), "rate", math.NaN())
+	entry.setTime(time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.UTC))
+	entry.file = "main.go"
+	entry.line = 12
+	entry.function = "main.main"
+
+	str := string(encoder.Encode(nil, entry))
+	want := `{"version":"1.1","host":` + strconv.Quote(processHostname) + `,"short_message":"login failed","timestamp":1521816907.003,"level":3,` +
+		`"_file":"main.go","_line":12,"_function":"main.main","_pid":` + strconv.Itoa(processPid) + `,"_program":` + strconv.Quote(processProgram) +
+		`,"_test_region":"eu-Error","_user_id":"phachon","_fields.id":7,"_ok":"false","_err":"timeout","_rate":"NaN"}`
+	if str != want {
+		t.Errorf("gelf encoder error, want %s got %s", want, str)
+	}
+	if !json.Valid([]byte(str)) {
+		t.Errorf("gelf encoder json is not valid, got %s", str)
+	}
+}
+
+func TestAppendGELFTimestamp(t *testing.T) {
+
+	now := time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.UTC)
+	tests := []struct {
+		precision time.Duration
+		want      string
+	}{
+		{time.Second, "1521816907"},
+		{time.Millisecond, "1521816907.003"},
+		{time.Microsecond, "1521816907.003123"},
+		{time.Nanosecond, "1521816907.003123456"},
+	}
+	for _, test := range tests {
+		str := string(appendGELFTimestamp(nil, now, test.precision))
+		if str != test.want {
+			t.Errorf("gelf timestamp of %s error, want %s got %s", test.precision, test.want, str)
+		}
+	}
+}
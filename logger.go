@@ -1,13 +1,16 @@
 package go_logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,12 +27,22 @@ const (
 	LOGGER_LEVEL_DEBUG
 )
 
+// stack trace level disabled
+const LOGGER_STACK_DISABLED = -1
+
 type adapterLoggerFunc func() LoggerAbstract
 
+// optional adapter interface, async workers write a batch of messages by one WriteBatch
+// entries must not be retained after WriteBatch returns
+type LoggerBatchWriter interface {
+	WriteBatch(entries []*Entry) error
+}
+
+// logger adapter, entries are pooled and must not be retained after Write returns
 type LoggerAbstract interface {
 	Name() string
 	Init(config Config) error
-	Write(loggerMsg *loggerMessage) error
+	Write(entry *Entry) error
 	Flush()
 }
 
@@ -48,6 +61,9 @@ var levelStringMapping = map[int]string{
 
 var defaultLoggerMessageFormat = "%millisecond_format% [%level_string%] %body%"
 
+//max wait of the flush before Fatal exits and Panic panics, a hung output does not block them
+var exitFlushTimeout = 5 * time.Second
+
 //Register logger adapter
 func Register(adapterName string, newLog adapterLoggerFunc) {
 	if adapters[adapterName] != nil {
@@ -61,243 +77,745 @@ func Register(adapterName string, newLog adapterLoggerFunc) {
 }
 
 type Logger struct {
-	lock        sync.Mutex          //sync lock
-	outputs     []*outputLogger     // outputs loggers
-	msgChan     chan *loggerMessage // message channel
-	synchronous bool                // is sync
-	wait        sync.WaitGroup      // process wait
-	signalChan  chan string
+	*loggerCore         // outputs, message channel and levels shared with derived loggers
+	fields      []Field // bound fields, written with every message
+	callerSkip  int     // caller frames skipped above the log method
+	noCaller    bool    // caller lookup is disabled
+}
+
+type loggerCore struct {
+	sequence  uint64        // sequence number of the last message, first field for 64-bit atomic alignment
+	lock      sync.Mutex    // lock of outputs changes
+	outputs   atomic.Value  // []*outputLogger snapshot, replaced by a copy under lock, loaded without lock
+	stateLock sync.RWMutex  // write lock for SetAsync and Close, read lock for Writer and Flush
+	queue     *asyncQueue   // async queue, nil is sync
+	closed    int32         // 1 is closed, read and written atomically
+	closing   chan struct{} // closed by Close, writers waiting in async queues return ErrLoggerClosed
+	level     int32         // minimum level, read and written atomically
+	stack     int32         // stack trace level, read and written atomically
+	goroutine int32         // 1 is goroutine id captured, read and written atomically
+	exit      atomic.Value  // func(code int) of Fatal, default os.Exit
 }
 
 type outputLogger struct {
 	Name  string
-	Level int
+	level int32       // output level, read and written atomically
+	queue *asyncQueue // output async queue, nil is written by logger, output is copied to change queue
 	LoggerAbstract
 }
 
-type loggerMessage struct {
-	Timestamp         int64  `json:"timestamp"`
-	TimestampFormat   string `json:"timestamp_format"`
-	Millisecond       int64  `json:"millisecond"`
-	MillisecondFormat string `json:"millisecond_format"`
-	Level             int    `json:"level"`
-	LevelString       string `json:"level_string"`
-	Body              string `json:"body"`
-	File              string `json:"file"`
-	Line              int    `json:"line"`
-	Function          string `json:"function"`
-}
-
 //new logger
 //return logger
 func NewLogger() *Logger {
 	logger := &Logger{
-		outputs:     []*outputLogger{},
-		msgChan:     make(chan *loggerMessage, 10),
-		synchronous: true,
-		wait:        sync.WaitGroup{},
-		signalChan:  make(chan string, 1),
+		loggerCore: &loggerCore{
+			level:   LOGGER_LEVEL_DEBUG,
+			stack:   LOGGER_STACK_DISABLED,
+			closing: make(chan struct{}),
+		},
 	}
+	logger.outputs.Store([]*outputLogger{})
+	logger.exit.Store(os.Exit)
 	//default adapter console
-	logger.attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{})
+	logger.attach("console", "console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{})
 
 	return logger
 }
 
-//start attach a logger adapter
+//start attach a logger adapter, the output name is the adapter name
 //param : adapterName console | file | database | ...
-//return : error
+//return : error, *DuplicateOutputError | *IllegalLevelError | *UnknownAdapterError | *AdapterInitError
 func (logger *Logger) Attach(adapterName string, level int, config Config) error {
 	logger.lock.Lock()
 	defer logger.lock.Unlock()
 
-	return logger.attach(adapterName, level, config)
+	return logger.attach(adapterName, adapterName, level, config)
+}
+
+//start attach a logger adapter as a named output, the same adapter can be attached under different names
+//param : outputName app | audit | ..., adapterName console | file | database | ...
+//return : error, *DuplicateOutputError | *IllegalLevelError | *UnknownAdapterError | *AdapterInitError
+func (logger *Logger) AttachNamed(outputName string, adapterName string, level int, config Config) error {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	return logger.attach(outputName, adapterName, level, config)
 }
 
 //attach a logger adapter after lock
-//param : adapterName console | file | database | ...
+//param : outputName, adapterName console | file | database | ...
 //return : error
-func (logger *Logger) attach(adapterName string, level int, config Config) error {
-	for _, output := range logger.outputs {
-		if output.Name == adapterName {
-			printError("logger: adapter " + adapterName + "already attached!")
-		}
+func (logger *Logger) attach(outputName string, adapterName string, level int, config Config) error {
+	if logger.getOutput(outputName) != nil {
+		return &DuplicateOutputError{Name: outputName}
+	}
+	if levelStringMapping[level] == "" {
+		return &IllegalLevelError{Level: level}
 	}
 	logFun, ok := adapters[adapterName]
 	if !ok {
-		printError("logger: adapter " + adapterName + "is nil!")
+		return &UnknownAdapterError{Adapter: adapterName}
 	}
 	adapterLog := logFun()
 	err := adapterLog.Init(config)
 	if err != nil {
-		printError("logger: adapter " + adapterName + " init failed, error: " + err.Error())
+		return &AdapterInitError{Adapter: adapterName, Err: err}
 	}
 
 	output := &outputLogger{
-		Name:           adapterName,
-		Level:          level,
+		Name:           outputName,
+		level:          int32(level),
 		LoggerAbstract: adapterLog,
 	}
 
-	logger.outputs = append(logger.outputs, output)
+	outputs := logger.loadOutputs()
+	logger.outputs.Store(append(outputs[:len(outputs):len(outputs)], output))
 	return nil
 }
 
-//start attach a logger adapter
-//param : adapterName console | file | database | ...
-//return : error
-func (logger *Logger) Detach(adapterName string) error {
+//detach a logger output, the output is flushed and closed if the adapter implements io.Closer
+//param : outputName, the adapterName if attached by Attach
+//return : error, the first output close error
+func (logger *Logger) Detach(outputName string) error {
 	logger.lock.Lock()
 	defer logger.lock.Unlock()
 
-	return logger.detach(adapterName)
+	return logger.detach(outputName)
 }
 
-//detach a logger adapter after lock
-//param : adapterName console | file | database | ...
+//detach a logger output after lock
+//param : outputName
 //return : error
-func (logger *Logger) detach(adapterName string) error {
+func (logger *Logger) detach(outputName string) error {
 	outputs := []*outputLogger{}
-	for _, output := range logger.outputs {
-		if output.Name == adapterName {
+	detached := []*outputLogger{}
+	for _, output := range logger.loadOutputs() {
+		if output.Name == outputName {
+			detached = append(detached, output)
 			continue
 		}
 		outputs = append(outputs, output)
 	}
-	logger.outputs = outputs
+	logger.outputs.Store(outputs)
+
+	// detached outputs are flushed and closed after the queued messages are written
+	var closeErr error
+	for _, output := range detached {
+		if output.queue != nil && !output.queue.isStopped() {
+			output.queue.stop()
+		}
+		output.Flush()
+		closer, ok := output.LoggerAbstract.(io.Closer)
+		if !ok {
+			continue
+		}
+		err := closer.Close()
+		if err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
+
+//logger is closed by Close or CloseContext
+//return : bool
+func (logger *Logger) isClosed() bool {
+	return atomic.LoadInt32(&logger.closed) == 1
+}
+
+//load outputs snapshot, must not be modified
+//return : []*outputLogger
+func (logger *Logger) loadOutputs() []*outputLogger {
+	return logger.outputs.Load().([]*outputLogger)
+}
+
+//get output from outputs snapshot
+//param : outputName
+//return : *outputLogger, nil if output is not attached
+func (logger *Logger) getOutput(outputName string) *outputLogger {
+	for _, output := range logger.loadOutputs() {
+		if output.Name == outputName {
+			return output
+		}
+	}
+	return nil
+}
+
+//get the adapter of a logger output
+//param : outputName
+//return : LoggerAbstract, nil if output is not attached
+func (logger *Logger) Output(outputName string) LoggerAbstract {
+	output := logger.getOutput(outputName)
+	if output == nil {
+		return nil
+	}
+	return output.LoggerAbstract
+}
+
+//get attached output names
+//return : []string
+func (logger *Logger) OutputNames() []string {
+	outputs := logger.loadOutputs()
+	names := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		names = append(names, output.Name)
+	}
+	return names
+}
+
+//derive a logger with bound fields, the derived logger shares outputs, async queue and levels
+//fields with the same key are merged, the last value wins
+//params : keyvals "key", value, ... or Fields
+//return : *Logger
+func (logger *Logger) With(keyvals ...interface{}) *Logger {
+	fields := makeFields(keyvals)
+	if len(logger.fields) > 0 {
+		fields = append(logger.fields[:len(logger.fields):len(logger.fields)], fields...)
+	}
+	child := logger.clone()
+	child.fields = mergeFields(fields)
+	return child
+}
+
+//derive a logger skipping more caller frames, used by wrapper functions of logger
+//%file%, %line% and %function% are the caller of the wrapper
+//params : skip int, the number of wrapper functions
+//return : *Logger
+func (logger *Logger) AddCallerSkip(skip int) *Logger {
+	child := logger.clone()
+	child.callerSkip += skip
+	return child
+}
+
+//derive a logger with caller lookup enabled or disabled, disabled caller is faster
+//%file%, %function% are "null" and %line% is 0 if disabled
+//params : enabled bool
+//return : *Logger
+func (logger *Logger) WithCaller(enabled bool) *Logger {
+	child := logger.clone()
+	child.noCaller = !enabled
+	return child
+}
+
+//copy logger, the copy shares loggerCore
+func (logger *Logger) clone() *Logger {
+	child := *logger
+	return &child
+}
+
+//set logger minimum level at runtime, messages above level are not written to any output
+//params : level int
+//return : error, *IllegalLevelError
+func (logger *Logger) SetLevel(level int) error {
+	if levelStringMapping[level] == "" {
+		return &IllegalLevelError{Level: level}
+	}
+	atomic.StoreInt32(&logger.level, int32(level))
 	return nil
 }
 
-//set logger level
+//get logger minimum level
+//return : level int
+func (logger *Logger) GetLevel() int {
+	return int(atomic.LoadInt32(&logger.level))
+}
+
+//level is enabled if it is not above the logger level and at least one output level
 //params : level int
-//func (logger *Logger) SetLevel(level int) {
-//	logger.level = level
-//}
+//return : bool
+func (logger *Logger) Enabled(level int) bool {
+	if int32(level) > atomic.LoadInt32(&logger.level) {
+		return false
+	}
+	for _, output := range logger.loadOutputs() {
+		if int32(level) <= atomic.LoadInt32(&output.level) {
+			return true
+		}
+	}
+	return false
+}
 
-//set logger synchronous false
-//params : sync bool
-func (logger *Logger) SetAsync(data ...int) {
+//set exit function called by Fatal after flush, tests replace os.Exit to keep the process
+//params : exit func(code int), nil is os.Exit
+func (logger *Logger) SetExitFunc(exit func(code int)) {
+	if exit == nil {
+		exit = os.Exit
+	}
+	logger.exit.Store(exit)
+}
+
+//set stack trace level, messages of level and more severe levels capture the stack trace of the caller
+//params : level int, LOGGER_STACK_DISABLED disables stack trace
+//return : error, *IllegalLevelError
+func (logger *Logger) SetStackLevel(level int) error {
+	if level != LOGGER_STACK_DISABLED && levelStringMapping[level] == "" {
+		return &IllegalLevelError{Level: level}
+	}
+	atomic.StoreInt32(&logger.stack, int32(level))
+	return nil
+}
+
+//capture goroutine id of messages for %goroutine% token and goroutine json key, default false
+//the goroutine id is parsed from runtime.Stack, it is slow
+//params : enable bool
+func (logger *Logger) SetGoroutineId(enable bool) {
+	if enable {
+		atomic.StoreInt32(&logger.goroutine, 1)
+	} else {
+		atomic.StoreInt32(&logger.goroutine, 0)
+	}
+}
+
+//set output level at runtime
+//params : outputName, level int
+//return : error, *UnknownOutputError | *IllegalLevelError
+func (logger *Logger) SetOutputLevel(outputName string, level int) error {
+	if levelStringMapping[level] == "" {
+		return &IllegalLevelError{Level: level}
+	}
 	logger.lock.Lock()
 	defer logger.lock.Unlock()
-	logger.synchronous = false
 
-	msgChanLen := 100
-	if len(data) > 0 {
-		msgChanLen = data[0]
+	output := logger.getOutput(outputName)
+	if output == nil {
+		return &UnknownOutputError{Name: outputName}
 	}
+	atomic.StoreInt32(&output.level, int32(level))
+	return nil
+}
 
-	logger.msgChan = make(chan *loggerMessage, msgChanLen)
-	logger.signalChan = make(chan string, 1)
+//get output level
+//params : outputName
+//return : level int, error *UnknownOutputError
+func (logger *Logger) GetOutputLevel(outputName string) (int, error) {
+	output := logger.getOutput(outputName)
+	if output == nil {
+		return 0, &UnknownOutputError{Name: outputName}
+	}
+	return int(atomic.LoadInt32(&output.level)), nil
+}
 
-	if !logger.synchronous {
-		go func() {
-			defer func() {
-				e := recover()
-				if e != nil {
-					fmt.Printf("%v", e)
-				}
-			}()
-			logger.startAsyncWrite()
-		}()
+//set logger synchronous false, start the async worker with block policy
+//if logger is already async, the old worker writes queued messages and stops
+//params : msgChanLen int, default 100
+func (logger *Logger) SetAsync(data ...int) {
+	asyncConfig := &AsyncConfig{}
+	if len(data) > 0 {
+		asyncConfig.ChanLen = data[0]
 	}
+	logger.SetAsyncConfig(asyncConfig)
 }
 
-//write log message
-//params : level int, msg string
+//set logger synchronous false, start the async worker by config
+//if logger is already async, the old worker writes queued messages and stops
+//params : config *AsyncConfig
 //return : error
-func (logger *Logger) Writer(level int, msg string) error {
-	funcName := "null"
-	pc, file, line, ok := runtime.Caller(2)
-	if !ok {
-		file = "null"
-		line = 0
-	} else {
-		funcName = runtime.FuncForPC(pc).Name()
+func (logger *Logger) SetAsyncConfig(config *AsyncConfig) error {
+	asyncConfig, err := checkAsyncConfig(config)
+	if err != nil {
+		return err
+	}
+
+	logger.stateLock.Lock()
+	defer logger.stateLock.Unlock()
+	if logger.isClosed() {
+		return ErrLoggerClosed
+	}
+	if logger.queue != nil {
+		logger.queue.stop()
+	}
+	logger.queue = newAsyncQueue(asyncConfig, logger.closing, logger.writeBatchToOutputs, logger.flushOutputs)
+	return nil
+}
+
+//set output async, the output is written by its own queue and worker
+//if output is already async, the old worker writes queued messages and stops
+//params : outputName, config *AsyncConfig
+//return : error, *UnknownOutputError | ErrLoggerClosed
+func (logger *Logger) SetOutputAsync(outputName string, config *AsyncConfig) error {
+	asyncConfig, err := checkAsyncConfig(config)
+	if err != nil {
+		return err
+	}
+
+	logger.stateLock.RLock()
+	defer logger.stateLock.RUnlock()
+	if logger.isClosed() {
+		return ErrLoggerClosed
+	}
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	// copy the output with the new queue, then stop the old queue
+	oldOutputs := logger.loadOutputs()
+	outputs := make([]*outputLogger, len(oldOutputs))
+	copy(outputs, oldOutputs)
+	for i, output := range outputs {
+		if output.Name != outputName {
+			continue
+		}
+		asyncOutput := &outputLogger{
+			Name:           output.Name,
+			level:          atomic.LoadInt32(&output.level),
+			LoggerAbstract: output.LoggerAbstract,
+		}
+		asyncOutput.queue = newAsyncQueue(asyncConfig, logger.closing, asyncOutput.writeBatch, asyncOutput.Flush)
+		asyncOutput.queue.name = outputName
+		outputs[i] = asyncOutput
+		logger.outputs.Store(outputs)
+
+		if output.queue != nil {
+			output.queue.stop()
+		}
+		return nil
 	}
-	_, filename := path.Split(file)
+	return &UnknownOutputError{Name: outputName}
+}
 
+//the number of messages dropped by output async policy since SetOutputAsync, by level
+//params : outputName
+//return : map[level]dropped, error *UnknownOutputError
+func (logger *Logger) OutputDropped(outputName string) (map[int]int64, error) {
+	output := logger.getOutput(outputName)
+	if output == nil {
+		return nil, &UnknownOutputError{Name: outputName}
+	}
+	if output.queue == nil {
+		return map[int]int64{}, nil
+	}
+	return output.queue.droppedCount(), nil
+}
+
+//the number of messages dropped by async policy since SetAsync, by level
+//return : map[level]dropped
+func (logger *Logger) Dropped() map[int]int64 {
+	logger.stateLock.RLock()
+	defer logger.stateLock.RUnlock()
+
+	if logger.queue == nil {
+		return map[int]int64{}
+	}
+	return logger.queue.droppedCount()
+}
+
+//write log message, the caller is the function calling the log method, see AddCallerSkip
+//params : level int, msg string, keyvals "key", value, ... or Fields
+//return : error, *IllegalLevelError | ErrLoggerClosed | ErrMessageDropped
+func (logger *Logger) Writer(level int, msg string, keyvals ...interface{}) error {
 	if levelStringMapping[level] == "" {
-		printError("logger: level " + strconv.Itoa(level) + " is illegal!")
+		return &IllegalLevelError{Level: level}
+	}
+	if !logger.Enabled(level) {
+		return nil
+	}
+
+	funcName := "null"
+	filename := "null"
+	fullPath := "null"
+	line := 0
+	if !logger.noCaller {
+		// runtime.Callers with a stack array does not allocate as runtime.Caller
+		pcs := [1]uintptr{}
+		if runtime.Callers(3+logger.callerSkip, pcs[:]) == 1 {
+			fn := runtime.FuncForPC(pcs[0] - 1)
+			if fn != nil {
+				funcName = fn.Name()
+				file, fileLine := fn.FileLine(pcs[0] - 1)
+				_, filename = path.Split(file)
+				fullPath = file
+				line = fileLine
+			}
+		}
 	}
 
-	loggerMsg := &loggerMessage{
-		Timestamp:         time.Now().Unix(),
-		TimestampFormat:   time.Now().Format("2006-01-02 15:04:05"),
-		Millisecond:       time.Now().UnixNano() / 1e6,
-		MillisecondFormat: time.Now().Format("2006-01-02 15:04:05.999"),
-		Level:             level,
-		LevelString:       levelStringMapping[level],
-		Body:              msg,
-		File:              filename,
-		Line:              line,
-		Function:          funcName,
+	loggerMsg := getEntry()
+	loggerMsg.setTime(time.Now())
+	loggerMsg.level = level
+	loggerMsg.body = msg
+	loggerMsg.file = filename
+	loggerMsg.line = line
+	loggerMsg.function = funcName
+	loggerMsg.path = fullPath
+	loggerMsg.sequence = atomic.AddUint64(&logger.sequence, 1)
+	if atomic.LoadInt32(&logger.goroutine) == 1 {
+		loggerMsg.goroutine = goroutineId()
+	}
+	loggerMsg.fields = logger.messageFields(loggerMsg, keyvals)
+	if int32(level) <= atomic.LoadInt32(&logger.stack) {
+		loggerMsg.stack = callerStack(3 + logger.callerSkip)
 	}
+	// async queues hold their own references, the entry is put back to the pool after written
+	defer loggerMsg.release()
 
-	if !logger.synchronous {
-		logger.wait.Add(1)
-		logger.msgChan <- loggerMsg
-	} else {
-		logger.writeToOutputs(loggerMsg)
+	// a closing logger waits for in-flight writers, new writers return without waiting
+	if logger.isClosed() {
+		return ErrLoggerClosed
+	}
+
+	logger.stateLock.RLock()
+	defer logger.stateLock.RUnlock()
+	if logger.isClosed() {
+		return ErrLoggerClosed
+	}
+	if logger.queue != nil {
+		return logger.queue.push(loggerMsg)
 	}
+	logger.writeToOutputs(loggerMsg)
 
 	return nil
 }
 
-//sync write message to loggerOutputs
-//params : loggerMessage
-func (logger *Logger) writeToOutputs(loggerMsg *loggerMessage) {
-	for _, loggerOutput := range logger.outputs {
+//stack trace of the caller
+//params : skip int, frames skipped of runtime.Callers
+//return : string, "function\n\tfile:line\n" of every frame
+func callerStack(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := ""
+	for {
+		frame, more := frames.Next()
+		stack += frame.Function + "\n\t" + frame.File + ":" + strconv.Itoa(frame.Line) + "\n"
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+//bound fields followed by message fields, message fields are appended to the fields buffer of the pooled entry
+//a message field overrides the bound field of the same key
+func (logger *Logger) messageFields(loggerMsg *Entry, keyvals []interface{}) []Field {
+	if len(keyvals) == 0 {
+		return logger.fields
+	}
+	loggerMsg.fieldsBuf = mergeFields(appendFields(append(loggerMsg.fieldsBuf[:0], logger.fields...), keyvals))
+	return loggerMsg.fieldsBuf
+}
+
+//sync write message to loggerOutputs, async outputs queue the message
+//params : entry
+func (logger *Logger) writeToOutputs(loggerMsg *Entry) {
+	for _, loggerOutput := range logger.loadOutputs() {
 		// write level
-		if loggerOutput.Level >= loggerMsg.Level {
-			err := loggerOutput.Write(loggerMsg)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "logger: unable write loggerMessage to adapter:%v, error: %v\n", loggerOutput.Name, err)
-			}
+		if int(atomic.LoadInt32(&loggerOutput.level)) < loggerMsg.level {
+			continue
 		}
+		if loggerOutput.queue != nil {
+			loggerOutput.queue.push(loggerMsg)
+			continue
+		}
+		loggerOutput.write(loggerMsg)
 	}
 }
 
-//start async write by read logger.msgChan
-func (logger *Logger) startAsyncWrite() {
-	for {
-		select {
-		case loggerMsg := <-logger.msgChan:
-			logger.writeToOutputs(loggerMsg)
-			logger.wait.Done()
-		case signal := <-logger.signalChan:
-			if signal == "flush" {
-				logger.flush()
+//write a batch of messages to loggerOutputs, async outputs queue the messages
+//params : entries
+func (logger *Logger) writeBatchToOutputs(entries []*Entry) {
+	for _, loggerOutput := range logger.loadOutputs() {
+		if loggerOutput.queue == nil {
+			loggerOutput.writeBatch(entries)
+			continue
+		}
+		level := int(atomic.LoadInt32(&loggerOutput.level))
+		for _, loggerMsg := range entries {
+			if level >= loggerMsg.level {
+				loggerOutput.queue.pushWait(loggerMsg)
 			}
 		}
 	}
 }
 
-//flush msgChan data
-func (logger *Logger) flush() {
-	if !logger.synchronous {
-		for {
-			if len(logger.msgChan) > 0 {
-				loggerMsg := <-logger.msgChan
-				logger.writeToOutputs(loggerMsg)
-				logger.wait.Done()
-				continue
+//write a batch of messages to output adapter by output level
+//adapters implement LoggerBatchWriter receive the batch by one WriteBatch
+//params : entries
+func (output *outputLogger) writeBatch(entries []*Entry) {
+	level := int(atomic.LoadInt32(&output.level))
+	outputEntries := entries
+	for i, loggerMsg := range entries {
+		if level >= loggerMsg.level {
+			continue
+		}
+		// copy the messages of output level
+		outputEntries = append(make([]*Entry, 0, len(entries)), entries[:i]...)
+		for _, loggerMsg := range entries[i+1:] {
+			if level >= loggerMsg.level {
+				outputEntries = append(outputEntries, loggerMsg)
 			}
-			break
 		}
-		for _, loggerOutput := range logger.outputs {
-			loggerOutput.Flush()
+		break
+	}
+
+	if len(outputEntries) == 0 {
+		return
+	}
+	batchWriter, ok := output.LoggerAbstract.(LoggerBatchWriter)
+	if !ok || len(outputEntries) == 1 {
+		for _, loggerMsg := range outputEntries {
+			output.write(loggerMsg)
+		}
+		return
+	}
+	err := batchWriter.WriteBatch(outputEntries)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: unable write entries to adapter:%v, error: %v\n", output.Name, err)
+	}
+}
+
+//write message to output adapter
+//params : entry
+func (output *outputLogger) write(loggerMsg *Entry) {
+	err := output.Write(loggerMsg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: unable write entry to adapter:%v, error: %v\n", output.Name, err)
+	}
+}
+
+//flush sync outputs, async outputs are flushed by their worker
+func (logger *Logger) flushOutputs() {
+	for _, loggerOutput := range logger.loadOutputs() {
+		if loggerOutput.queue != nil {
+			continue
+		}
+		loggerOutput.Flush()
+	}
+}
+
+//async output queues
+func (logger *Logger) outputQueues() []*asyncQueue {
+	queues := []*asyncQueue{}
+	for _, loggerOutput := range logger.loadOutputs() {
+		if loggerOutput.queue != nil {
+			queues = append(queues, loggerOutput.queue)
 		}
 	}
+	return queues
+}
+
+//the number of messages not written in queue and async output queues
+func (logger *Logger) undelivered(queue *asyncQueue) int {
+	undelivered := 0
+	if queue != nil {
+		undelivered += queue.undelivered()
+	}
+	for _, outputQueue := range logger.outputQueues() {
+		undelivered += outputQueue.undelivered()
+	}
+	return undelivered
 }
 
-//if SetAsync() or logger.synchronous is false, must call Flush() to flush msgChan data
+//write queued messages and flush all outputs, outputs are usable after Flush
+//if SetAsync(), must call Flush() or Close() to write msgChan data before the end of process
 func (logger *Logger) Flush() {
-	if !logger.synchronous {
-		logger.signalChan <- "flush"
-		logger.wait.Wait()
-		return
+	logger.FlushContext(context.Background())
+}
+
+//write queued messages and flush all outputs, then write and flush async output queues, return when ctx is done
+//the flush continues in the async worker after ctx is done, logger is usable
+//return : undelivered int, the number of queued messages not written, error ctx.Err() | ErrLoggerClosed
+func (logger *Logger) FlushContext(ctx context.Context) (int, error) {
+	logger.stateLock.RLock()
+	defer logger.stateLock.RUnlock()
+	if logger.isClosed() {
+		return 0, ErrLoggerClosed
+	}
+
+	if logger.queue != nil {
+		_, err := logger.queue.flushContext(ctx)
+		if err != nil {
+			return logger.undelivered(logger.queue), err
+		}
+	} else {
+		done := make(chan struct{})
+		go func() {
+			logger.flushOutputs()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return logger.undelivered(nil), ctx.Err()
+		}
+	}
+	for _, outputQueue := range logger.outputQueues() {
+		_, err := outputQueue.flushContext(ctx)
+		if err != nil {
+			return logger.undelivered(logger.queue), err
+		}
+	}
+	return 0, nil
+}
+
+//write queued messages, stop async worker, flush and close all outputs
+//subsequent log calls return ErrLoggerClosed
+//return : error, ErrLoggerClosed if already closed or the first output close error
+func (logger *Logger) Close() error {
+	_, err := logger.CloseContext(context.Background())
+	return err
+}
+
+//write queued messages, stop async worker and async output workers, flush and close all outputs, return when ctx is done
+//logger is closed even if ctx is done, the worker stops and outputs are closed after the queued messages are written
+//writers blocked by a full async queue return ErrLoggerClosed, the message is not queued
+//return : undelivered int, the number of queued messages not written, error ctx.Err() | ErrLoggerClosed | the first output close error
+func (logger *Logger) CloseContext(ctx context.Context) (int, error) {
+	if !atomic.CompareAndSwapInt32(&logger.closed, 0, 1) {
+		return 0, ErrLoggerClosed
+	}
+	// writers blocked by a full async queue give up, the lock is not held by a writer waiting for a hung output
+	close(logger.closing)
+
+	// after closed, Writer, Flush and SetAsync do not use the async queue
+	var closeErr error
+	queueChan := make(chan *asyncQueue, 1)
+	done := make(chan struct{})
+	go func() {
+		logger.stateLock.Lock()
+		queue := logger.queue
+		logger.stateLock.Unlock()
+		queueChan <- queue
+
+		if queue != nil {
+			queue.stop()
+		}
+		for _, outputQueue := range logger.outputQueues() {
+			outputQueue.stop()
+		}
+		closeErr = logger.closeOutputs()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return 0, closeErr
+	case <-ctx.Done():
+	}
+	var queue *asyncQueue
+	select {
+	case queue = <-queueChan:
+	default:
+	}
+	return logger.undelivered(queue), ctx.Err()
+}
+
+//flush and close all outputs, adapters implement io.Closer are closed
+func (logger *Logger) closeOutputs() error {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	var closeErr error
+	for _, loggerOutput := range logger.loadOutputs() {
+		loggerOutput.Flush()
+		closer, ok := loggerOutput.LoggerAbstract.(io.Closer)
+		if !ok {
+			continue
+		}
+		err := closer.Close()
+		if err != nil && closeErr == nil {
+			closeErr = err
+		}
 	}
-	logger.flush()
+	return closeErr
 }
 
 func (logger *Logger) LoggerLevel(levelStr string) int {
@@ -324,110 +842,233 @@ func (logger *Logger) LoggerLevel(levelStr string) int {
 	}
 }
 
-func loggerMessageFormat(format string, loggerMsg *loggerMessage) string {
-	message := strings.Replace(format, "%timestamp%", strconv.FormatInt(loggerMsg.Timestamp, 10), 1)
-	message = strings.Replace(message, "%timestamp_format%", loggerMsg.TimestampFormat, 1)
-	message = strings.Replace(message, "%millisecond%", strconv.FormatInt(loggerMsg.Millisecond, 10), 1)
-	message = strings.Replace(message, "%millisecond_format%", loggerMsg.MillisecondFormat, 1)
-	message = strings.Replace(message, "%level%", strconv.Itoa(loggerMsg.Level), 1)
-	message = strings.Replace(message, "%level_string%", loggerMsg.LevelString, 1)
-	message = strings.Replace(message, "%file%", loggerMsg.File, 1)
-	message = strings.Replace(message, "%line%", strconv.Itoa(loggerMsg.Line), 1)
-	message = strings.Replace(message, "%function%", loggerMsg.Function, 1)
-	message = strings.Replace(message, "%body%", loggerMsg.Body, 1)
+//log fatal, write emergency level, flush all outputs and async queues, then exit 1
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Fatal(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_EMERGENCY, msg, keyvals...)
+	logger.flushBeforeExit()
+	logger.exit.Load().(func(int))(1)
+}
 
-	return message
+//log fatal format, write emergency level, flush all outputs and async queues, then exit 1
+func (logger *Logger) Fatalf(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	logger.Writer(LOGGER_LEVEL_EMERGENCY, msg)
+	logger.flushBeforeExit()
+	logger.exit.Load().(func(int))(1)
+}
+
+//log panic, write critical level, flush all outputs and async queues, then panic with msg
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Panic(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_CRITICAL, msg, keyvals...)
+	logger.flushBeforeExit()
+	panic(msg)
+}
+
+//log panic format, write critical level, flush all outputs and async queues, then panic with msg
+func (logger *Logger) Panicf(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	logger.Writer(LOGGER_LEVEL_CRITICAL, msg)
+	logger.flushBeforeExit()
+	panic(msg)
+}
+
+//flush all outputs and async queues before exit or panic, return after exitFlushTimeout
+func (logger *Logger) flushBeforeExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), exitFlushTimeout)
+	defer cancel()
+	logger.FlushContext(ctx)
 }
 
 //log emergency level
-func (logger *Logger) Emergency(msg string) {
-	logger.Writer(LOGGER_LEVEL_EMERGENCY, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Emergency(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_EMERGENCY, msg, keyvals...)
 }
 
-//log emergency format
+//log emergency format, format is not evaluated if emergency level is not enabled
 func (logger *Logger) Emergencyf(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_EMERGENCY) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_EMERGENCY, msg)
 }
 
+//log emergency lazy message, msgFunc is called only if emergency level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) EmergencyFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_EMERGENCY) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_EMERGENCY, msgFunc(), keyvals...)
+}
+
 //log alert level
-func (logger *Logger) Alert(msg string) {
-	logger.Writer(LOGGER_LEVEL_ALERT, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Alert(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_ALERT, msg, keyvals...)
 }
 
-//log alert format
+//log alert format, format is not evaluated if alert level is not enabled
 func (logger *Logger) Alertf(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_ALERT) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_ALERT, msg)
 }
 
+//log alert lazy message, msgFunc is called only if alert level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) AlertFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_ALERT) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_ALERT, msgFunc(), keyvals...)
+}
+
 //log critical level
-func (logger *Logger) Critical(msg string) {
-	logger.Writer(LOGGER_LEVEL_CRITICAL, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Critical(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_CRITICAL, msg, keyvals...)
 }
 
-//log critical format
+//log critical format, format is not evaluated if critical level is not enabled
 func (logger *Logger) Criticalf(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_CRITICAL) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_CRITICAL, msg)
 }
 
+//log critical lazy message, msgFunc is called only if critical level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) CriticalFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_CRITICAL) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_CRITICAL, msgFunc(), keyvals...)
+}
+
 //log error level
-func (logger *Logger) Error(msg string) {
-	logger.Writer(LOGGER_LEVEL_ERROR, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Error(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_ERROR, msg, keyvals...)
 }
 
-//log error format
+//log error format, format is not evaluated if error level is not enabled
 func (logger *Logger) Errorf(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_ERROR) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_ERROR, msg)
 }
 
+//log error lazy message, msgFunc is called only if error level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) ErrorFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_ERROR) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_ERROR, msgFunc(), keyvals...)
+}
+
 //log warning level
-func (logger *Logger) Warning(msg string) {
-	logger.Writer(LOGGER_LEVEL_WARNING, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Warning(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_WARNING, msg, keyvals...)
 }
 
-//log warning format
+//log warning format, format is not evaluated if warning level is not enabled
 func (logger *Logger) Warningf(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_WARNING) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_WARNING, msg)
 }
 
+//log warning lazy message, msgFunc is called only if warning level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) WarningFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_WARNING) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_WARNING, msgFunc(), keyvals...)
+}
+
 //log notice level
-func (logger *Logger) Notice(msg string) {
-	logger.Writer(LOGGER_LEVEL_NOTICE, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Notice(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_NOTICE, msg, keyvals...)
 }
 
-//log notice format
+//log notice format, format is not evaluated if notice level is not enabled
 func (logger *Logger) Noticef(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_NOTICE) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_NOTICE, msg)
 }
 
+//log notice lazy message, msgFunc is called only if notice level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) NoticeFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_NOTICE) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_NOTICE, msgFunc(), keyvals...)
+}
+
 //log info level
-func (logger *Logger) Info(msg string) {
-	logger.Writer(LOGGER_LEVEL_INFO, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Info(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_INFO, msg, keyvals...)
 }
 
-//log info format
+//log info format, format is not evaluated if info level is not enabled
 func (logger *Logger) Infof(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_INFO) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_INFO, msg)
 }
 
+//log info lazy message, msgFunc is called only if info level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) InfoFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_INFO) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_INFO, msgFunc(), keyvals...)
+}
+
 //log debug level
-func (logger *Logger) Debug(msg string) {
-	logger.Writer(LOGGER_LEVEL_DEBUG, msg)
+//params : msg string, keyvals "key", value, ... or Fields
+func (logger *Logger) Debug(msg string, keyvals ...interface{}) {
+	logger.Writer(LOGGER_LEVEL_DEBUG, msg, keyvals...)
 }
 
-//log debug format
+//log debug format, format is not evaluated if debug level is not enabled
 func (logger *Logger) Debugf(format string, a ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_DEBUG) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.Writer(LOGGER_LEVEL_DEBUG, msg)
 }
 
-func printError(message string) {
-	fmt.Println(message)
-	os.Exit(0)
+//log debug lazy message, msgFunc is called only if debug level is enabled
+//params : msgFunc func() string, keyvals "key", value, ... or Fields
+func (logger *Logger) DebugFunc(msgFunc func() string, keyvals ...interface{}) {
+	if !logger.Enabled(LOGGER_LEVEL_DEBUG) {
+		return
+	}
+	logger.Writer(LOGGER_LEVEL_DEBUG, msgFunc(), keyvals...)
 }
@@ -1,27 +1,165 @@
 package go_logger
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
+// adapter blocks Write until release is closed
+type testBlockAdapter struct {
+	release chan struct{}
+	count   int64
+	lock    sync.Mutex
+	bodies  []string
+}
+
+type testBlockConfig struct {
+	release chan struct{}
+}
+
+func (c *testBlockConfig) Name() string {
+	return "test_block"
+}
+
+func (a *testBlockAdapter) Init(config Config) error {
+	a.release = config.(*testBlockConfig).release
+	return nil
+}
+
+func (a *testBlockAdapter) Write(entry *Entry) error {
+	<-a.release
+	atomic.AddInt64(&a.count, 1)
+	a.lock.Lock()
+	a.bodies = append(a.bodies, entry.Body())
+	a.lock.Unlock()
+	return nil
+}
+
+func (a *testBlockAdapter) Flush() {
+}
+
+func (a *testBlockAdapter) Name() string {
+	return "test_block"
+}
+
+// closed release channel, test_block adapter does not block
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+	Register("test_block", func() LoggerAbstract {
+		return &testBlockAdapter{}
+	})
+}
+
 func TestNewLogger(t *testing.T) {
 	NewLogger()
 }
 
 func TestLogger_Attach(t *testing.T) {
 
+	dir, remove := testTempDir(t)
+	defer remove()
 	logger := NewLogger()
 	fileConfig := &FileConfig{
-		Filename: "./test.log",
+		Filename: filepath.Join(dir, "test.log"),
 	}
-	logger.Attach("file", LOGGER_LEVEL_DEBUG, fileConfig)
-	outputs := logger.outputs
-	for _, outputLogger := range outputs {
-		if outputLogger.Name != "file" {
-			t.Error("file attach failed")
-		}
+	err := logger.Attach("file", LOGGER_LEVEL_DEBUG, fileConfig)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	outputs := logger.loadOutputs()
+	if len(outputs) != 2 || outputs[1].Name != "file" {
+		t.Error("file attach failed")
+	}
+}
+
+func TestLogger_AttachNamed(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "go-logger")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	logger := NewLogger()
+	defer logger.Close()
+	err = logger.AttachNamed("app", "file", LOGGER_LEVEL_DEBUG, &FileConfig{Filename: filepath.Join(dir, "app.log")})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = logger.AttachNamed("audit", "file", LOGGER_LEVEL_INFO, &FileConfig{Filename: filepath.Join(dir, "audit.log"), JsonFormat: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = logger.AttachNamed("audit", "console", LOGGER_LEVEL_INFO, &ConsoleConfig{})
+	if _, ok := err.(*DuplicateOutputError); !ok {
+		t.Errorf("attach named duplicate output error, got %v", err)
+	}
+	if strings.Join(logger.OutputNames(), ",") != "console,app,audit" {
+		t.Errorf("attach named output names error, got %v", logger.OutputNames())
+	}
+	if logger.Output("audit") == nil || logger.Output("audit").Name() != FILE_ADAPTER_NAME {
+		t.Error("attach named output lookup error")
+	}
+
+	logger.Detach("app")
+	if strings.Join(logger.OutputNames(), ",") != "console,audit" {
+		t.Errorf("detach named output error, got %v", logger.OutputNames())
+	}
+	if logger.Output("app") != nil {
+		t.Error("detach named output lookup error")
+	}
+}
+
+func TestLogger_AttachError(t *testing.T) {
+
+	logger := NewLogger()
+
+	err := logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{})
+	if _, ok := err.(*DuplicateOutputError); !ok {
+		t.Errorf("attach duplicate output error, got %v", err)
+	}
+	err = logger.Attach("database", LOGGER_LEVEL_DEBUG, &ConsoleConfig{})
+	if _, ok := err.(*UnknownAdapterError); !ok {
+		t.Errorf("attach unknown adapter error, got %v", err)
+	}
+	err = logger.Attach("file", 100, &FileConfig{Filename: "./test.log"})
+	if _, ok := err.(*IllegalLevelError); !ok {
+		t.Errorf("attach illegal level error, got %v", err)
+	}
+	err = logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{Filename: "./test.log", DateSlice: "x"})
+	initErr, ok := err.(*AdapterInitError)
+	if !ok || initErr.Adapter != "file" || initErr.Unwrap() == nil {
+		t.Errorf("attach adapter init error, got %v", err)
+	}
+	err = logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{Filename: "/nonexistent-dir/x/app.log"})
+	if _, ok := err.(*AdapterInitError); !ok {
+		t.Errorf("attach file open error, got %v", err)
+	}
+	if len(logger.loadOutputs()) != 1 {
+		t.Error("attach error must not add output")
+	}
+}
+
+func TestLogger_WriterError(t *testing.T) {
+
+	logger := NewLogger()
+
+	err := logger.Writer(100, "illegal level")
+	if _, ok := err.(*IllegalLevelError); !ok {
+		t.Errorf("writer illegal level error, got %v", err)
 	}
 }
 
@@ -30,13 +168,258 @@ func TestLogger_Detach(t *testing.T) {
 	logger := NewLogger()
 	logger.Detach("console")
 
-	outputs := logger.outputs
+	outputs := logger.loadOutputs()
 
 	if len(outputs) > 0 {
 		t.Error("logger detach error")
 	}
 }
 
+func TestLogger_DetachClose(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "go-logger")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	logger := NewLogger()
+	err = logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{Filename: filepath.Join(dir, "test.log")})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	logger.SetOutputAsync("file", nil)
+	adapter := logger.Output("file").(*AdapterFile)
+
+	logger.Info("message")
+	err = logger.Detach("file")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if adapter.write[FILE_ACCESS_LEVEL].writer != nil {
+		t.Error("detach must close the output")
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "test.log"))
+	if !strings.Contains(string(content), "message") {
+		t.Errorf("detach must write async output queue, got %s", content)
+	}
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+
+	logger := NewLogger()
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+
+	err := logger.SetLevel(LOGGER_LEVEL_INFO)
+	if err != nil || logger.GetLevel() != LOGGER_LEVEL_INFO {
+		t.Fatal("logger set level error")
+	}
+	logger.Debug("debug message")
+	logger.With("service", "api").Debug("child debug message")
+	logger.Info("info message")
+	if strings.Contains(buf.String(), "debug message") || !strings.Contains(buf.String(), "info message") {
+		t.Errorf("logger level filter error, got %s", buf.String())
+	}
+
+	if _, ok := logger.SetLevel(100).(*IllegalLevelError); !ok {
+		t.Error("logger set illegal level error")
+	}
+}
+
+func TestLogger_SetOutputLevel(t *testing.T) {
+
+	logger := NewLogger()
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+
+	err := logger.SetOutputLevel("console", LOGGER_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	level, err := logger.GetOutputLevel("console")
+	if err != nil || level != LOGGER_LEVEL_ERROR {
+		t.Fatal("logger get output level error")
+	}
+	logger.Warning("warning message")
+	logger.Error("error message")
+	if strings.Contains(buf.String(), "warning message") || !strings.Contains(buf.String(), "error message") {
+		t.Errorf("logger output level filter error, got %s", buf.String())
+	}
+
+	if _, ok := logger.SetOutputLevel("file", LOGGER_LEVEL_ERROR).(*UnknownOutputError); !ok {
+		t.Error("logger set unknown output level error")
+	}
+	if _, ok := logger.SetOutputLevel("console", -1).(*IllegalLevelError); !ok {
+		t.Error("logger set output illegal level error")
+	}
+}
+
+func TestLogger_Flush(t *testing.T) {
+
+	os.Remove("./flush.log")
+	defer os.Remove("./flush.log")
+
+	logger := NewLogger()
+	logger.Detach("console")
+	err := logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{Filename: "./flush.log"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	logger.SetAsync()
+
+	logger.Info("before flush")
+	logger.Flush()
+	logger.Info("after flush")
+	logger.Flush()
+
+	content, _ := ioutil.ReadFile("./flush.log")
+	if strings.Count(string(content), "flush") != 2 {
+		t.Errorf("logger flush error, got %s", content)
+	}
+	logger.Close()
+}
+
+func TestLogger_Close(t *testing.T) {
+
+	os.Remove("./close.log")
+	defer os.Remove("./close.log")
+
+	logger := NewLogger()
+	logger.Detach("console")
+	err := logger.Attach("file", LOGGER_LEVEL_DEBUG, &FileConfig{Filename: "./close.log"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	logger.SetAsync(10)
+	logger.SetAsync(10)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				logger.Info("close message")
+			}
+		}()
+	}
+	wg.Wait()
+
+	err = logger.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	content, _ := ioutil.ReadFile("./close.log")
+	if strings.Count(string(content), "close message") != 100 {
+		t.Errorf("logger close must write queued messages, got %d", strings.Count(string(content), "close message"))
+	}
+
+	if logger.Writer(LOGGER_LEVEL_INFO, "closed message") != ErrLoggerClosed {
+		t.Error("logger write after close error")
+	}
+	if logger.Close() != ErrLoggerClosed {
+		t.Error("logger close twice error")
+	}
+	logger.Flush()
+}
+
+func TestLogger_FlushContext(t *testing.T) {
+
+	release := make(chan struct{})
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: release})
+	logger.SetAsync()
+
+	logger.Info("message 1")
+	logger.Info("message 2")
+	logger.Info("message 3")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	undelivered, err := logger.FlushContext(ctx)
+	if err != context.DeadlineExceeded || undelivered != 3 {
+		t.Errorf("logger flush context timeout error, got %d %v", undelivered, err)
+	}
+
+	close(release)
+	undelivered, err = logger.FlushContext(context.Background())
+	if err != nil || undelivered != 0 {
+		t.Errorf("logger flush context error, got %d %v", undelivered, err)
+	}
+	logger.Info("message 4")
+	logger.Flush()
+	if atomic.LoadInt64(&logger.Output("test_block").(*testBlockAdapter).count) != 4 {
+		t.Error("logger must be usable after flush context timeout")
+	}
+	logger.Close()
+}
+
+func TestLogger_CloseContext(t *testing.T) {
+
+	release := make(chan struct{})
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: release})
+	logger.SetAsync()
+
+	logger.Info("message 1")
+	logger.Info("message 2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	undelivered, err := logger.CloseContext(ctx)
+	if err != context.DeadlineExceeded || undelivered != 2 {
+		t.Errorf("logger close context timeout error, got %d %v", undelivered, err)
+	}
+	if logger.Writer(LOGGER_LEVEL_INFO, "closed message") != ErrLoggerClosed {
+		t.Error("logger must be closed after close context timeout")
+	}
+	if _, err := logger.FlushContext(context.Background()); err != ErrLoggerClosed {
+		t.Error("logger flush after close context error")
+	}
+	close(release)
+}
+
+func TestLogger_CloseContextBlockedWriters(t *testing.T) {
+
+	release := make(chan struct{})
+	defer close(release)
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: release})
+	logger.SetAsync(1)
+
+	// the worker waits for the hung output, the queue is full, writers wait in the queue
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("message")
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() {
+		_, err := logger.CloseContext(ctx)
+		closed <- err
+	}()
+	select {
+	case err := <-closed:
+		if err != context.DeadlineExceeded {
+			t.Errorf("logger close context blocked writers error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("logger close context must return when writers are blocked")
+	}
+	wg.Wait()
+}
+
 func TestLogger_LoggerLevel(t *testing.T) {
 
 	logger := NewLogger()
@@ -63,23 +446,347 @@ func TestLogger_LoggerLevel(t *testing.T) {
 	}
 }
 
-func TestLogger_loggerMessageFormat(t *testing.T) {
+func TestLogger_FormatEntry(t *testing.T) {
 
-	loggerMsg := &loggerMessage{
-		Timestamp:         time.Now().Unix(),
-		TimestampFormat:   time.Now().Format("2006-01-02 15:04:05"),
-		Millisecond:       time.Now().UnixNano() / 1e6,
-		MillisecondFormat: time.Now().Format("2006-01-02 15:04:05.999"),
-		Level:             LOGGER_LEVEL_DEBUG,
-		LevelString:       "debug",
-		Body:              "logger console adapter test",
-		File:              "console_test.go",
-		Line:              77,
-		Function:          "TestAdapterConsole_WriteJsonFormat",
+	loggerMsg := &Entry{
+		time:     time.Now(),
+		level:    LOGGER_LEVEL_DEBUG,
+		body:     "logger console adapter test",
+		file:     "console_test.go",
+		line:     77,
+		function: "TestAdapterConsole_WriteJsonFormat",
 	}
 
 	format := "%millisecond_format% [%level_string%] [%file%:%line%] %body%"
-	str := loggerMessageFormat(format, loggerMsg)
+	str := FormatEntry(format, loggerMsg)
 
 	fmt.Println(str)
 }
+
+func TestLogger_FormatEntryFields(t *testing.T) {
+
+	loggerMsg := &Entry{
+		level:  LOGGER_LEVEL_INFO,
+		body:   "login ok",
+		fields: makeFields([]interface{}{"user", "phachon", "ms", 12}),
+	}
+
+	str := FormatEntry("[%level_string%] %body%", loggerMsg)
+	if str != "[Info] login ok user=phachon ms=12" {
+		t.Errorf("FormatEntry fields error, got %s", str)
+	}
+	str = FormatEntry("[%level_string%] %fields% %body%", loggerMsg)
+	if str != "[Info] user=phachon ms=12 login ok" {
+		t.Errorf("FormatEntry fields token error, got %s", str)
+	}
+}
+
+func TestLogger_With(t *testing.T) {
+
+	logger := NewLogger()
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+
+	child := logger.With("service", "api").With(Fields{"request_id": "r1"})
+	child.Info("login ok", "user", "phachon")
+	logger.Info("parent")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logger with output error, got %s", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], "[Info] login ok service=api request_id=r1 user=phachon") {
+		t.Errorf("logger with fields error, got %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "[Info] parent") {
+		t.Errorf("logger with parent fields error, got %s", lines[1])
+	}
+	if child.loggerCore != logger.loggerCore {
+		t.Error("logger with must share outputs")
+	}
+}
+
+func TestLogger_WithOverride(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{JsonFormat: true})
+	buf := &bytes.Buffer{}
+	logger.Output("console").(*AdapterConsole).write.writer = buf
+
+	child := logger.With("a", 1, "b", 1).With("b", 2)
+	child.Info("x", "a", 2, "c", 3, "c", 4)
+	child.Info("y")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logger with override output error, got %s", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], `"a":2,"b":2,"c":4}`) || strings.Count(lines[0], `"a":`) != 1 {
+		t.Errorf("logger message fields must override bound fields, got %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `"a":1,"b":2}`) || strings.Count(lines[1], `"b":`) != 1 {
+		t.Errorf("logger with must override bound fields, got %s", lines[1])
+	}
+}
+
+func TestLogger_WithConcurrent(t *testing.T) {
+
+	logger := NewLogger()
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = &bytes.Buffer{}
+	parent := logger.With("service", "api")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			child := parent.With("request_id", i)
+			child.Info("request", "n", i)
+			if len(child.fields) != 2 || child.fields[1].Value != i {
+				t.Errorf("logger with concurrent fields error, got %v", child.fields)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// go test -race -run=ConcurrentAttach
+func TestLogger_ConcurrentAttachDetach(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.AttachNamed("buffer", "console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{})
+	logger.Output("buffer").(*AdapterConsole).write.writer = &bytes.Buffer{}
+
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			child := logger.With("goroutine", i)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				child.Info("concurrent message")
+				logger.Debug("concurrent message")
+			}
+		}(i)
+	}
+
+	for i := 0; i < 50; i++ {
+		err := logger.AttachNamed("block", "test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: closedChan})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		logger.SetOutputLevel("block", LOGGER_LEVEL_INFO)
+		logger.SetOutputAsync("block", &AsyncConfig{ChanLen: 10})
+		logger.SetOutputLevel("buffer", i%8)
+		logger.OutputNames()
+		logger.Detach("block")
+	}
+	close(stop)
+	wg.Wait()
+	logger.Close()
+}
+
+// wrapper of logger, reports the caller of testLogWrapper by AddCallerSkip
+func testLogWrapper(logger *Logger, msg string) {
+	logger.AddCallerSkip(1).Info(msg)
+}
+
+func TestLogger_AddCallerSkip(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{Format: "%file%:%line% %function% %body%"})
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+
+	_, _, line, _ := runtime.Caller(0)
+	testLogWrapper(logger, "wrapper message")
+	logger.Info("direct message")
+	logger.WithCaller(false).Info("no caller message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != fmt.Sprintf("logger_test.go:%d github.com/phachon/go-logger.TestLogger_AddCallerSkip wrapper message", line+1) {
+		t.Errorf("logger add caller skip error, got %s", lines[0])
+	}
+	if lines[1] != fmt.Sprintf("logger_test.go:%d github.com/phachon/go-logger.TestLogger_AddCallerSkip direct message", line+2) {
+		t.Errorf("logger caller error, got %s", lines[1])
+	}
+	if lines[2] != "null:0 null no caller message" {
+		t.Errorf("logger without caller error, got %s", lines[2])
+	}
+}
+
+func TestLogger_SetStackLevel(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{JsonFormat: true})
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+
+	err := logger.SetStackLevel(10)
+	if _, ok := err.(*IllegalLevelError); !ok {
+		t.Errorf("logger set stack level error, got %v", err)
+	}
+	err = logger.SetStackLevel(LOGGER_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	logger.Warning("no stack")
+	logger.Error("stack")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	data := map[string]interface{}{}
+	json.Unmarshal([]byte(lines[0]), &data)
+	if _, ok := data["stack"]; ok {
+		t.Errorf("logger stack below stack level error, got %s", lines[0])
+	}
+	data = map[string]interface{}{}
+	json.Unmarshal([]byte(lines[1]), &data)
+	stack, _ := data["stack"].(string)
+	if !strings.HasPrefix(stack, "github.com/phachon/go-logger.TestLogger_SetStackLevel\n\t") {
+		t.Errorf("logger stack error, got %s", stack)
+	}
+
+	logger.SetStackLevel(LOGGER_STACK_DISABLED)
+	buf.Reset()
+	logger.Emergency("no stack")
+	if strings.Contains(buf.String(), `"stack"`) {
+		t.Errorf("logger stack disabled error, got %s", buf.String())
+	}
+}
+
+func TestLogger_Fatal(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.AttachNamed("block", "test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: closedChan})
+	logger.SetOutputAsync("block", &AsyncConfig{ChanLen: 10})
+	logger.SetAsync()
+	exitCode := -1
+	logger.SetExitFunc(func(code int) {
+		exitCode = code
+	})
+
+	logger.Info("before fatal")
+	logger.Fatalf("fatal %s", "message")
+
+	if exitCode != 1 {
+		t.Errorf("logger fatal exit code error, got %d", exitCode)
+	}
+	adapter := logger.Output("block").(*testBlockAdapter)
+	if atomic.LoadInt64(&adapter.count) != 2 {
+		t.Errorf("logger fatal must flush async queues, got %d messages", atomic.LoadInt64(&adapter.count))
+	}
+	logger.Close()
+}
+
+func TestLogger_Panic(t *testing.T) {
+
+	logger := NewLogger()
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+
+	defer func() {
+		e := recover()
+		if e != "panic message" {
+			t.Errorf("logger panic value error, got %v", e)
+		}
+		if !strings.Contains(buf.String(), "[Critical] panic message") {
+			t.Errorf("logger panic output error, got %s", buf.String())
+		}
+	}()
+	logger.Panic("panic message")
+}
+
+func TestLogger_PanicAsync(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: closedChan})
+	logger.SetAsync()
+	defer logger.Close()
+
+	defer func() {
+		recover()
+		adapter := logger.Output("test_block").(*testBlockAdapter)
+		if atomic.LoadInt64(&adapter.count) != 2 {
+			t.Errorf("logger panic must flush async queue, got %d messages", atomic.LoadInt64(&adapter.count))
+		}
+	}()
+	logger.Info("before panic")
+	logger.Panic("panic message")
+}
+
+func TestLogger_FatalHungOutput(t *testing.T) {
+
+	defer func(timeout time.Duration) {
+		exitFlushTimeout = timeout
+	}(exitFlushTimeout)
+	exitFlushTimeout = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	defer close(release)
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("test_block", LOGGER_LEVEL_DEBUG, &testBlockConfig{release: release})
+	logger.SetAsync()
+	exited := make(chan int, 1)
+	logger.SetExitFunc(func(code int) {
+		exited <- code
+	})
+
+	go logger.Fatal("fatal message")
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("logger fatal exit code error, got %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("logger fatal must exit when an output is hung")
+	}
+}
+
+func TestLogger_Enabled(t *testing.T) {
+
+	logger := NewLogger()
+	buf := &bytes.Buffer{}
+	logger.loadOutputs()[0].LoggerAbstract.(*AdapterConsole).write.writer = buf
+	logger.SetOutputLevel("console", LOGGER_LEVEL_INFO)
+
+	if !logger.Enabled(LOGGER_LEVEL_INFO) || logger.Enabled(LOGGER_LEVEL_DEBUG) {
+		t.Error("logger enabled by output level error")
+	}
+	logger.SetLevel(LOGGER_LEVEL_ERROR)
+	if logger.Enabled(LOGGER_LEVEL_INFO) || !logger.Enabled(LOGGER_LEVEL_ERROR) {
+		t.Error("logger enabled by logger level error")
+	}
+	logger.SetLevel(LOGGER_LEVEL_DEBUG)
+
+	called := 0
+	msgFunc := func() string {
+		called++
+		return "lazy message"
+	}
+	logger.DebugFunc(msgFunc)
+	if called != 0 || buf.Len() != 0 {
+		t.Errorf("logger debug func must not be called, called %d", called)
+	}
+	logger.InfoFunc(msgFunc, "user", "phachon")
+	if called != 1 || !strings.HasSuffix(strings.TrimSpace(buf.String()), "[Info] lazy message user=phachon") {
+		t.Errorf("logger info func error, got %s", buf.String())
+	}
+
+	logger.Detach("console")
+	if logger.Enabled(LOGGER_LEVEL_EMERGENCY) {
+		t.Error("logger without outputs must not be enabled")
+	}
+}
@@ -0,0 +1,101 @@
+package go_logger
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// process metadata, read once at init
+var (
+	processHostname string
+	processPid      int
+	processProgram  string
+	processDir      string // working directory with '/' separator
+)
+
+// module paths of the build, longest first, to find the module of a package
+var buildModulePaths []string
+
+func init() {
+	processHostname, _ = os.Hostname()
+	processPid = os.Getpid()
+	processProgram = filepath.Base(os.Args[0])
+	dir, err := os.Getwd()
+	if err == nil {
+		processDir = strings.TrimSuffix(filepath.ToSlash(dir), "/")
+	}
+
+	buildInfo, ok := debug.ReadBuildInfo()
+	if ok {
+		buildModulePaths = append(buildModulePaths, buildInfo.Main.Path)
+		for _, dep := range buildInfo.Deps {
+			buildModulePaths = append(buildModulePaths, dep.Path)
+		}
+	}
+	sort.Slice(buildModulePaths, func(i, j int) bool {
+		return len(buildModulePaths[i]) > len(buildModulePaths[j])
+	})
+}
+
+//id of the current goroutine, parsed from "goroutine 18 [running]:" of runtime.Stack, it is slow
+func goroutineId() uint64 {
+	buf := [64]byte{}
+	n := runtime.Stack(buf[:], false)
+	id := uint64(0)
+	for _, c := range buf[len("goroutine "):n] {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
+
+//package path of function name, "github.com/phachon/go-logger.(*Logger).Info" is "github.com/phachon/go-logger"
+func functionPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[slash+1:], '.')
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
+
+//function name without package path, "github.com/phachon/go-logger.(*Logger).Info" is "(*Logger).Info"
+func shortFunction(function string) string {
+	pkg := functionPackage(function)
+	if len(pkg) == len(function) {
+		return function
+	}
+	return function[len(pkg)+1:]
+}
+
+//append path of file relative to the module root of the caller package
+//file "/src/app/handler/user.go" of package "example.com/app/handler" in module "example.com/app" is "handler/user.go"
+//if the module is unknown as package main, path relative to the working directory or the full path is appended
+func appendRelativePath(dst []byte, file string, function string) []byte {
+	pkg := functionPackage(function)
+	for _, module := range buildModulePaths {
+		if module == "" || !strings.HasPrefix(pkg, module) {
+			continue
+		}
+		if len(pkg) != len(module) && pkg[len(module)] != '/' {
+			continue
+		}
+		// package directory relative to module root
+		if len(pkg) > len(module) {
+			dst = append(dst, pkg[len(module)+1:]...)
+			dst = append(dst, '/')
+		}
+		return append(dst, path.Base(file)...)
+	}
+	if processDir != "" && strings.HasPrefix(file, processDir) && len(file) > len(processDir) && file[len(processDir)] == '/' {
+		return append(dst, file[len(processDir)+1:]...)
+	}
+	return append(dst, file...)
+}
@@ -0,0 +1,97 @@
+package go_logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFunctionPackage(t *testing.T) {
+
+	functions := [][3]string{
+		{"github.com/phachon/go-logger.(*Logger).Info", "github.com/phachon/go-logger", "(*Logger).Info"},
+		{"github.com/phachon/go-logger/utils.NewMisc", "github.com/phachon/go-logger/utils", "NewMisc"},
+		{"main.main.func1", "main", "main.func1"},
+		{"null", "null", "null"},
+	}
+	for _, function := range functions {
+		if pkg := functionPackage(function[0]); pkg != function[1] {
+			t.Errorf("function package of %s error, got %s", function[0], pkg)
+		}
+		if short := shortFunction(function[0]); short != function[2] {
+			t.Errorf("short function of %s error, got %s", function[0], short)
+		}
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+
+	path := string(appendRelativePath(nil, "/src/go-logger/utils/misc.go", "github.com/phachon/go-logger/utils.NewMisc"))
+	if path != "utils/misc.go" {
+		t.Errorf("relative path of module package error, got %s", path)
+	}
+	path = string(appendRelativePath(nil, "/src/go-logger/logger.go", "github.com/phachon/go-logger.NewLogger"))
+	if path != "logger.go" {
+		t.Errorf("relative path of module root package error, got %s", path)
+	}
+	path = string(appendRelativePath(nil, "/other/main.go", "main.main"))
+	if path != "/other/main.go" {
+		t.Errorf("relative path of unknown module error, got %s", path)
+	}
+}
+
+func TestLogger_MetadataTokens(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{
+		Format: "%rel_path%|%package%|%short_function%|%hostname%|%pid%|%program%|%sequence%|%goroutine%",
+	})
+	buf := &bytes.Buffer{}
+	logger.Output("console").(*AdapterConsole).write.writer = buf
+
+	logger.Info("first")
+	logger.SetGoroutineId(true)
+	logger.With("service", "api").Info("second")
+
+	hostname, _ := os.Hostname()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	prefix := fmt.Sprintf("metadata_test.go|github.com/phachon/go-logger|TestLogger_MetadataTokens|%s|%d|%s|", hostname, os.Getpid(), processProgram)
+	if lines[0] != prefix+"1|0" {
+		t.Errorf("metadata tokens error, got %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], prefix+"2|") || strings.HasPrefix(lines[1], prefix+"2|0") {
+		t.Errorf("metadata tokens sequence and goroutine error, got %s", lines[1])
+	}
+}
+
+func TestLogger_MetadataJson(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{JsonFormat: true})
+	buf := &bytes.Buffer{}
+	logger.Output("console").(*AdapterConsole).write.writer = buf
+
+	_, file, _, _ := runtime.Caller(0)
+	logger.Info("json metadata")
+
+	data := map[string]interface{}{}
+	err := json.Unmarshal(buf.Bytes(), &data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if data["path"] != file || data["rel_path"] != "metadata_test.go" || data["short_function"] != "TestLogger_MetadataJson" {
+		t.Errorf("json metadata caller error, got %s", buf.String())
+	}
+	if data["pid"] != float64(os.Getpid()) || data["sequence"] != float64(1) {
+		t.Errorf("json metadata process error, got %s", buf.String())
+	}
+	if _, ok := data["goroutine"]; ok {
+		t.Errorf("json goroutine must not be captured by default, got %s", buf.String())
+	}
+}
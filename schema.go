@@ -0,0 +1,346 @@
+package go_logger
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// json schema of an output, entry keys of MarshalJSON are renamed, selected, omitted and nested
+//
+//	&go_logger.JsonSchema{
+//		Keys: map[string]string{
+//			"time":         "@timestamp",
+//			"body":         "message",
+//			"level_string": "log.level",
+//			"line":         "log.origin.file.line",
+//			"fields":       "labels",
+//		},
+//		Only:      []string{"time", "level_string", "body", "line", "fields"},
+//		Constants: map[string]interface{}{"service.name": "api"},
+//	}
+//
+// output:
+//
+//	{"@timestamp":"...","log":{"level":"Info","origin":{"file":{"line":12}}},"message":"login ok","labels":{"user":"phachon"},"service":{"name":"api"}}
+type JsonSchema struct {
+
+	// output key of an entry key, a dotted output key is nested, "log.origin.file.line" is {"log":{"origin":{"file":{"line":12}}}}
+	// entry keys are the keys of MarshalJSON, custom tokens of RegisterToken and "fields"
+	// "fields" renamed writes fields to the object of the output key, otherwise fields are written at the top level
+	Keys map[string]string
+
+	// entry keys written in order, empty is all keys in the order of MarshalJSON
+	Only []string
+
+	// entry keys not written
+	Omit []string
+
+	// constant keys written with every message after the entry keys, a dotted key is nested, values are json marshaled once
+	Constants map[string]interface{}
+}
+
+// json value of an entry key
+type jsonEntryKey struct {
+	name  string
+	write func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder)
+	empty func(entry *Entry) bool // key is not written if empty, nil is never empty
+}
+
+// entry keys in the order of MarshalJSON, custom tokens and fields are added by the schema
+var jsonEntryKeys = []jsonEntryKey{
+	{name: "timestamp", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Int64(entry.timestamp())
+	}},
+	{name: "timestamp_format", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.RawByte('"')
+		out.Buffer.Buf = entry.appendTimestampFormat(out.Buffer.Buf)
+		out.RawByte('"')
+	}},
+	{name: "millisecond", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Int64(entry.millisecond())
+	}},
+	{name: "millisecond_format", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.RawByte('"')
+		out.Buffer.Buf = entry.appendMillisecondFormat(out.Buffer.Buf)
+		out.RawByte('"')
+	}},
+	{name: "time", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.RawByte('"')
+		start := len(out.Buffer.Buf)
+		out.Buffer.Buf = timeEncoder.appendTime(out.Buffer.Buf, entry.time)
+		closeJSONString(out, start)
+	}},
+	{name: "time_unix", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Int64(timeEncoder.unix(entry.time))
+	}},
+	{name: "level", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Int(entry.level)
+	}},
+	{name: "level_string", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(entry.LevelString())
+	}},
+	{name: "body", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(entry.body)
+	}},
+	{name: "file", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(entry.file)
+	}},
+	{name: "line", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Int(entry.line)
+	}},
+	{name: "function", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(entry.function)
+	}},
+	{name: "path", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(entry.path)
+	}},
+	{name: "rel_path", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.RawByte('"')
+		start := len(out.Buffer.Buf)
+		out.Buffer.Buf = appendRelativePath(out.Buffer.Buf, entry.path, entry.function)
+		closeJSONString(out, start)
+	}},
+	{name: "package", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(functionPackage(entry.function))
+	}},
+	{name: "short_function", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(shortFunction(entry.function))
+	}},
+	{name: "hostname", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(processHostname)
+	}},
+	{name: "pid", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Int(processPid)
+	}},
+	{name: "program", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(processProgram)
+	}},
+	{name: "sequence", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Uint64(entry.sequence)
+	}},
+	{name: "goroutine", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.Uint64(entry.goroutine)
+	}, empty: func(entry *Entry) bool {
+		return entry.goroutine == 0
+	}},
+	{name: "stack", write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(entry.stack)
+	}, empty: func(entry *Entry) bool {
+		return entry.stack == ""
+	}},
+}
+
+var jsonWriterPool = sync.Pool{
+	New: func() interface{} {
+		return &jwriter.Writer{}
+	},
+}
+
+// compiled json schema, written by the json encoder
+type jsonSchemaEncoder struct {
+	nodes       []*jsonSchemaNode
+	topFields   bool            // fields are written at the top level
+	reserved    map[string]bool // top level keys, a top level field with the same key is renamed to "fields.key"
+	timeEncoder *timeEncoder
+}
+
+// key of the schema object, an entry key, a constant, the fields object or an object of nodes
+type jsonSchemaNode struct {
+	name     string
+	key      string // `"name":`
+	value    *jsonEntryKey
+	constant []byte
+	fields   bool
+	children []*jsonSchemaNode
+}
+
+//compile json schema
+//params : schema, timeEncoder of time and time_unix keys
+//return : *jsonSchemaEncoder, error if a key is unknown, an output key is duplicated or a constant is not json
+func newJsonSchemaEncoder(schema *JsonSchema, timeEncoder *timeEncoder) (*jsonSchemaEncoder, error) {
+	entryKeys := map[string]*jsonEntryKey{}
+	names := []string{}
+	for i := range jsonEntryKeys {
+		entryKeys[jsonEntryKeys[i].name] = &jsonEntryKeys[i]
+		names = append(names, jsonEntryKeys[i].name)
+	}
+	for _, token := range loadTokens().custom {
+		entryKeys[token.name] = token.jsonEntryKey()
+		names = append(names, token.name)
+	}
+	names = append(names, "fields")
+	isEntryKey := func(name string) bool {
+		return name == "fields" || entryKeys[name] != nil
+	}
+
+	for name := range schema.Keys {
+		if !isEntryKey(name) {
+			return nil, errors.New("config JsonSchema Keys key " + name + " is unknown!")
+		}
+	}
+	omit := map[string]bool{}
+	for _, name := range schema.Omit {
+		if !isEntryKey(name) {
+			return nil, errors.New("config JsonSchema Omit key " + name + " is unknown!")
+		}
+		omit[name] = true
+	}
+	if len(schema.Only) > 0 {
+		for _, name := range schema.Only {
+			if !isEntryKey(name) {
+				return nil, errors.New("config JsonSchema Only key " + name + " is unknown!")
+			}
+		}
+		names = schema.Only
+	}
+
+	encoder := &jsonSchemaEncoder{reserved: map[string]bool{}, timeEncoder: timeEncoder}
+	for _, name := range names {
+		if omit[name] {
+			continue
+		}
+		outputKey, renamed := schema.Keys[name]
+		if name == "fields" && !renamed {
+			encoder.topFields = true
+			continue
+		}
+		if !renamed {
+			outputKey = name
+		}
+		node := &jsonSchemaNode{value: entryKeys[name], fields: name == "fields"}
+		err := encoder.insert(outputKey, node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	constantKeys := make([]string, 0, len(schema.Constants))
+	for key := range schema.Constants {
+		constantKeys = append(constantKeys, key)
+	}
+	sort.Strings(constantKeys)
+	for _, key := range constantKeys {
+		data, err := json.Marshal(schema.Constants[key])
+		if err != nil {
+			return nil, errors.New("config JsonSchema Constants key " + key + " error, " + err.Error())
+		}
+		err = encoder.insert(key, &jsonSchemaNode{constant: data})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, node := range encoder.nodes {
+		encoder.reserved[node.name] = true
+	}
+	return encoder, nil
+}
+
+//json value of the custom token
+func (token customToken) jsonEntryKey() *jsonEntryKey {
+	value := token.value
+	return &jsonEntryKey{name: token.name, write: func(out *jwriter.Writer, entry *Entry, timeEncoder *timeEncoder) {
+		out.String(value(entry))
+	}}
+}
+
+//insert node of the dotted output key, objects of the key are created
+func (encoder *jsonSchemaEncoder) insert(outputKey string, node *jsonSchemaNode) error {
+	nodes := &encoder.nodes
+	parts := strings.Split(outputKey, ".")
+	for i, part := range parts {
+		if part == "" {
+			return errors.New("config JsonSchema output key " + strconv.Quote(outputKey) + " is illegal!")
+		}
+		var found *jsonSchemaNode
+		for _, child := range *nodes {
+			if child.name == part {
+				found = child
+				break
+			}
+		}
+		last := i == len(parts)-1
+		if found != nil && (last || found.children == nil) {
+			return errors.New("config JsonSchema output key " + outputKey + " is duplicated!")
+		}
+		if found == nil {
+			found = node
+			if !last {
+				found = &jsonSchemaNode{children: []*jsonSchemaNode{}}
+			}
+			found.name = part
+			found.key = jsonKey(part)
+			*nodes = append(*nodes, found)
+		}
+		nodes = &found.children
+	}
+	return nil
+}
+
+//json object key `"name":`
+func jsonKey(name string) string {
+	out := jwriter.Writer{}
+	out.String(name)
+	out.RawByte(':')
+	return string(out.Buffer.BuildBytes())
+}
+
+// Encode, append entry json object of the schema to dst
+func (encoder *jsonSchemaEncoder) Encode(dst []byte, entry *Entry) []byte {
+	// writer escapes by the key write funcs, it is pooled
+	out := jsonWriterPool.Get().(*jwriter.Writer)
+	out.Buffer.Buf = dst
+	out.RawByte('{')
+	first := encoder.appendNodes(out, encoder.nodes, entry, true)
+	if encoder.topFields {
+		encodeFieldsJSON(out, entry.fields, encoder.reserved, first)
+	}
+	out.RawByte('}')
+	dst = out.Buffer.BuildBytes()
+	*out = jwriter.Writer{}
+	jsonWriterPool.Put(out)
+	return dst
+}
+
+func (encoder *jsonSchemaEncoder) ContentType() string {
+	return "application/json"
+}
+
+//write keys of nodes, first is no key written before in the object
+//return : first after the nodes
+func (encoder *jsonSchemaEncoder) appendNodes(out *jwriter.Writer, nodes []*jsonSchemaNode, entry *Entry, first bool) bool {
+	for _, node := range nodes {
+		if node.value != nil && node.value.empty != nil && node.value.empty(entry) {
+			continue
+		}
+		if node.fields && len(entry.fields) == 0 {
+			continue
+		}
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString(node.key)
+		switch {
+		case node.children != nil:
+			out.RawByte('{')
+			encoder.appendNodes(out, node.children, entry, true)
+			out.RawByte('}')
+		case node.constant != nil:
+			out.Raw(node.constant, nil)
+		case node.fields:
+			out.RawByte('{')
+			encodeFieldsJSON(out, entry.fields, nil, true)
+			out.RawByte('}')
+		default:
+			node.value.write(out, entry, encoder.timeEncoder)
+		}
+	}
+	return first
+}
@@ -0,0 +1,101 @@
+package go_logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestJsonSchema_Encode(t *testing.T) {
+
+	encoder, err := newEncoder(ENCODER_JSON, false, &EncoderConfig{
+		TimeZone: "UTC",
+		JsonSchema: &JsonSchema{
+			Keys: map[string]string{
+				"time":         "@timestamp",
+				"body":         "message",
+				"level_string": "log.level",
+				"line":         "log.origin.file.line",
+				"file":         "log.origin.file.name",
+				"fields":       "labels",
+			},
+			Only:      []string{"time", "level_string", "body", "file", "line", "stack", "fields"},
+			Constants: map[string]interface{}{"service.name": "api", "log.logger": "go-logger"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	entry := NewEntry(LOGGER_LEVEL_INFO, "login ok", "user", "phachon", "body", 1)
+	entry.setTime(time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.UTC))
+	entry.file = "main.go"
+	entry.line = 12
+
+	str := string(encoder.Encode(nil, entry))
+	want := `{"@timestamp":"2018-03-23T14:55:07.003Z","log":{"level":"Info","origin":{"file":{"name":"main.go","line":12}},"logger":"go-logger"},"message":"login ok","labels":{"user":"phachon","body":1},"service":{"name":"api"}}`
+	if str != want {
+		t.Errorf("json schema encode error, want %s got %s", want, str)
+	}
+}
+
+func TestJsonSchema_OmitTopFields(t *testing.T) {
+
+	encoder, err := newEncoder(ENCODER_JSON, false, &EncoderConfig{
+		JsonSchema: &JsonSchema{
+			Keys: map[string]string{"body": "msg"},
+			Only: []string{"level", "body", "goroutine", "fields"},
+			Omit: []string{"level"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	entry := NewEntry(LOGGER_LEVEL_INFO, "login ok", "msg", "field", "user", "phachon")
+	str := string(encoder.Encode(nil, entry))
+	want := `{"msg":"login ok","fields.msg":"field","user":"phachon"}`
+	if str != want {
+		t.Errorf("json schema omit error, want %s got %s", want, str)
+	}
+}
+
+func TestJsonSchema_Error(t *testing.T) {
+
+	schemas := []*JsonSchema{
+		{Keys: map[string]string{"message": "msg"}},
+		{Omit: []string{"levels"}},
+		{Only: []string{"body", "bodyy"}},
+		{Keys: map[string]string{"body": "log.level", "level_string": "log.level"}},
+		{Keys: map[string]string{"body": "log", "level_string": "log.level"}},
+		{Keys: map[string]string{"body": "log..message"}},
+		{Constants: map[string]interface{}{"body": "constant"}},
+		{Constants: map[string]interface{}{"callback": func() {}}},
+	}
+	for _, schema := range schemas {
+		err := NewAdapterConsole().Init(&ConsoleConfig{JsonFormat: true, JsonSchema: schema})
+		if err == nil {
+			t.Errorf("json schema %+v must return error", schema)
+		}
+	}
+}
+
+func TestJsonSchema_ImpliesJsonEncoder(t *testing.T) {
+
+	consoleAdapter := NewAdapterConsole()
+	err := consoleAdapter.Init(&ConsoleConfig{JsonSchema: &JsonSchema{
+		Keys: map[string]string{"body": "message"},
+		Only: []string{"body"},
+	}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	buf := &bytes.Buffer{}
+	consoleAdapter.(*AdapterConsole).write.writer = buf
+
+	consoleAdapter.Write(NewEntry(LOGGER_LEVEL_INFO, "login ok"))
+	want := `{"message":"login ok"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("json schema without JsonFormat must use json encoder, want %s got %s", want, buf.String())
+	}
+}
@@ -0,0 +1,485 @@
+package go_logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const SYSLOG_ADAPTER_NAME = "syslog"
+
+const (
+	SYSLOG_NETWORK_UNIX = "unix"
+	SYSLOG_NETWORK_UDP  = "udp"
+	SYSLOG_NETWORK_TCP  = "tcp"
+	SYSLOG_NETWORK_TLS  = "tcp+tls"
+)
+
+const (
+	SYSLOG_RFC5424 = "5424"
+	SYSLOG_RFC3164 = "3164"
+)
+
+const (
+	SYSLOG_FRAMING_OCTET_COUNTING  = "octet-counting"
+	SYSLOG_FRAMING_NON_TRANSPARENT = "non-transparent"
+)
+
+// default SD-ID of the fields structured data, 32473 is the example enterprise number of RFC 5612
+const SYSLOG_FIELDS_SD_ID = "fields@32473"
+
+// local syslog sockets, tried in order if the config Address is empty
+var syslogUnixAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var syslogFacilityMapping = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// adapter syslog
+type AdapterSyslog struct {
+	lock     sync.Mutex
+	config   *SyslogConfig
+	conn     net.Conn
+	stream   bool // conn is a stream, messages are framed
+	priority int  // facility * 8, severity is the logger level
+	hostname string
+	appName  string
+	procId   string
+	msgId    string
+	sdId     string
+}
+
+// syslog config
+type SyslogConfig struct {
+
+	// network, "unix", "udp", "tcp", "tcp+tls", default "unix"
+	Network string
+
+	// syslog server address "host:port", or the unix socket path
+	// if unix address is empty, "/dev/log", "/var/run/syslog", "/var/run/log" are tried
+	Address string
+
+	// tls config of "tcp+tls"
+	TLSConfig *tls.Config
+
+	// message format, "5424" RFC 5424, "3164" RFC 3164 (BSD syslog), default "5424"
+	RFC string
+
+	// tcp framing, "octet-counting" RFC 6587 "LEN MSG", "non-transparent" "MSG\n", default "octet-counting"
+	// udp and unix messages are not framed
+	Framing string
+
+	// facility "kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv", "ftp",
+	// "local0" ... "local7", default "user"
+	Facility string
+
+	// hostname, default the hostname of the process
+	Hostname string
+
+	// APP-NAME of RFC 5424 or TAG of RFC 3164, default the program name
+	AppName string
+
+	// MSGID of RFC 5424, default "-"
+	MsgId string
+
+	// SD-ID of the fields structured data of RFC 5424, default "fields@32473"
+	// fields are written as [fields@32473 key="value" ...], RFC 3164 appends fields key=value pairs to the message
+	StructuredDataId string
+
+	// dial and write timeout, default 5s
+	Timeout time.Duration
+}
+
+func (sc *SyslogConfig) Name() string {
+	return SYSLOG_ADAPTER_NAME
+}
+
+func NewAdapterSyslog() LoggerAbstract {
+	return &AdapterSyslog{
+		config: &SyslogConfig{},
+	}
+}
+
+// init, the syslog server is connected
+func (adapterSyslog *AdapterSyslog) Init(syslogConfig Config) error {
+	if syslogConfig.Name() != SYSLOG_ADAPTER_NAME {
+		return errors.New("logger syslog adapter init error, config must SyslogConfig")
+	}
+
+	vc := reflect.ValueOf(syslogConfig)
+	sc := vc.Interface().(*SyslogConfig)
+	adapterSyslog.config = sc
+
+	if sc.Network == "" {
+		sc.Network = SYSLOG_NETWORK_UNIX
+	}
+	switch sc.Network {
+	case SYSLOG_NETWORK_UNIX:
+	case SYSLOG_NETWORK_UDP, SYSLOG_NETWORK_TCP, SYSLOG_NETWORK_TLS:
+		if sc.Address == "" {
+			return errors.New("config Address cannot be empty!")
+		}
+	default:
+		return errors.New("config Network must be one of the 'unix', 'udp', 'tcp', 'tcp+tls'!")
+	}
+	if sc.RFC == "" {
+		sc.RFC = SYSLOG_RFC5424
+	}
+	if sc.RFC != SYSLOG_RFC5424 && sc.RFC != SYSLOG_RFC3164 {
+		return errors.New("config RFC must be one of the '5424', '3164'!")
+	}
+	if sc.Framing == "" {
+		sc.Framing = SYSLOG_FRAMING_OCTET_COUNTING
+	}
+	if sc.Framing != SYSLOG_FRAMING_OCTET_COUNTING && sc.Framing != SYSLOG_FRAMING_NON_TRANSPARENT {
+		return errors.New("config Framing must be one of the 'octet-counting', 'non-transparent'!")
+	}
+	if sc.Facility == "" {
+		sc.Facility = "user"
+	}
+	facility, ok := syslogFacilityMapping[sc.Facility]
+	if !ok {
+		return errors.New("config Facility " + sc.Facility + " is unknown!")
+	}
+	if sc.Timeout == 0 {
+		sc.Timeout = 5 * time.Second
+	}
+
+	hostname := sc.Hostname
+	if hostname == "" {
+		hostname = processHostname
+	}
+	appName := sc.AppName
+	if appName == "" {
+		appName = processProgram
+	}
+	sdId := sc.StructuredDataId
+	if sdId == "" {
+		sdId = SYSLOG_FIELDS_SD_ID
+	}
+	adapterSyslog.priority = facility * 8
+	adapterSyslog.hostname = syslogHeaderField(hostname, 255)
+	adapterSyslog.appName = syslogHeaderField(appName, 48)
+	adapterSyslog.procId = strconv.Itoa(processPid)
+	adapterSyslog.msgId = syslogHeaderField(sc.MsgId, 32)
+	adapterSyslog.sdId = syslogSDName(sdId)
+
+	adapterSyslog.lock.Lock()
+	defer adapterSyslog.lock.Unlock()
+	return adapterSyslog.connect()
+}
+
+// Write, the syslog server is reconnected and the message is written again if the write fails
+func (adapterSyslog *AdapterSyslog) Write(loggerMsg *Entry) error {
+	return adapterSyslog.WriteBatch([]*Entry{loggerMsg})
+}
+
+// WriteBatch, messages of a stream are written by one write, datagrams are written one by one
+func (adapterSyslog *AdapterSyslog) WriteBatch(entries []*Entry) error {
+
+	adapterSyslog.lock.Lock()
+	defer adapterSyslog.lock.Unlock()
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if adapterSyslog.conn == nil {
+		err := adapterSyslog.connect()
+		if err != nil {
+			return err
+		}
+	}
+	if adapterSyslog.stream {
+		for _, loggerMsg := range entries {
+			buf.bs = adapterSyslog.appendFrame(buf.bs, loggerMsg)
+		}
+		return adapterSyslog.write(buf.bs)
+	}
+	for _, loggerMsg := range entries {
+		buf.bs = adapterSyslog.appendMessage(buf.bs[:0], loggerMsg)
+		err := adapterSyslog.write(buf.bs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush
+func (adapterSyslog *AdapterSyslog) Flush() {
+
+}
+
+// Close, close the connection
+func (adapterSyslog *AdapterSyslog) Close() error {
+	adapterSyslog.lock.Lock()
+	defer adapterSyslog.lock.Unlock()
+
+	if adapterSyslog.conn == nil {
+		return nil
+	}
+	err := adapterSyslog.conn.Close()
+	adapterSyslog.conn = nil
+	return err
+}
+
+// Name
+func (adapterSyslog *AdapterSyslog) Name() string {
+	return SYSLOG_ADAPTER_NAME
+}
+
+// write data, if the write fails the syslog server is reconnected and data is written again
+func (adapterSyslog *AdapterSyslog) write(data []byte) error {
+	err := adapterSyslog.writeConn(data)
+	if err == nil {
+		return nil
+	}
+	adapterSyslog.conn.Close()
+	adapterSyslog.conn = nil
+	connErr := adapterSyslog.connect()
+	if connErr != nil {
+		return errors.New("syslog write error: " + err.Error() + ", reconnect error: " + connErr.Error())
+	}
+	err = adapterSyslog.writeConn(data)
+	if err != nil {
+		adapterSyslog.conn.Close()
+		adapterSyslog.conn = nil
+	}
+	return err
+}
+
+func (adapterSyslog *AdapterSyslog) writeConn(data []byte) error {
+	if adapterSyslog.config.Network != SYSLOG_NETWORK_UNIX {
+		adapterSyslog.conn.SetWriteDeadline(time.Now().Add(adapterSyslog.config.Timeout))
+	}
+	_, err := adapterSyslog.conn.Write(data)
+	return err
+}
+
+// connect the syslog server by config
+func (adapterSyslog *AdapterSyslog) connect() error {
+	config := adapterSyslog.config
+	dialer := &net.Dialer{Timeout: config.Timeout}
+
+	switch config.Network {
+	case SYSLOG_NETWORK_UNIX:
+		addresses := syslogUnixAddresses
+		if config.Address != "" {
+			addresses = []string{config.Address}
+		}
+		var err error
+		for _, address := range addresses {
+			for _, network := range []string{"unixgram", "unix"} {
+				var conn net.Conn
+				conn, err = dialer.Dial(network, address)
+				if err == nil {
+					adapterSyslog.conn = conn
+					adapterSyslog.stream = network == "unix"
+					return nil
+				}
+			}
+		}
+		return errors.New("syslog unix socket connect error: " + err.Error())
+	case SYSLOG_NETWORK_TLS:
+		conn, err := tls.DialWithDialer(dialer, "tcp", config.Address, config.TLSConfig)
+		if err != nil {
+			return err
+		}
+		adapterSyslog.conn = conn
+		adapterSyslog.stream = true
+	default:
+		conn, err := dialer.Dial(config.Network, config.Address)
+		if err != nil {
+			return err
+		}
+		adapterSyslog.conn = conn
+		adapterSyslog.stream = config.Network == SYSLOG_NETWORK_TCP
+	}
+	return nil
+}
+
+// append framed message of a stream, unix stream messages end with '\n'
+func (adapterSyslog *AdapterSyslog) appendFrame(dst []byte, loggerMsg *Entry) []byte {
+	if adapterSyslog.config.Network == SYSLOG_NETWORK_UNIX || adapterSyslog.config.Framing == SYSLOG_FRAMING_NON_TRANSPARENT {
+		dst = adapterSyslog.appendMessage(dst, loggerMsg)
+		return append(dst, '\n')
+	}
+	// octet-counting, the length is written after the message is appended
+	start := len(dst)
+	dst = adapterSyslog.appendMessage(dst, loggerMsg)
+	end := len(dst)
+	length := strconv.Itoa(end - start)
+	dst = append(dst, length...)
+	dst = append(dst, ' ')
+	copy(dst[start+len(length)+1:], dst[start:end])
+	copy(dst[start:], length)
+	dst[start+len(length)] = ' '
+	return dst
+}
+
+// append message of the config RFC
+func (adapterSyslog *AdapterSyslog) appendMessage(dst []byte, loggerMsg *Entry) []byte {
+	if adapterSyslog.config.RFC == SYSLOG_RFC3164 {
+		return adapterSyslog.appendRFC3164(dst, loggerMsg)
+	}
+	return adapterSyslog.appendRFC5424(dst, loggerMsg)
+}
+
+// append RFC 5424 message
+//
+//	<14>1 2018-03-23T14:55:07.003123+08:00 web-1 app 1024 - [fields@32473 user="phachon"] login ok
+func (adapterSyslog *AdapterSyslog) appendRFC5424(dst []byte, loggerMsg *Entry) []byte {
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(adapterSyslog.priority+loggerMsg.level), 10)
+	dst = append(dst, ">1 "...)
+	dst = loggerMsg.time.AppendFormat(dst, "2006-01-02T15:04:05.000000Z07:00")
+	dst = append(dst, ' ')
+	dst = append(dst, adapterSyslog.hostname...)
+	dst = append(dst, ' ')
+	dst = append(dst, adapterSyslog.appName...)
+	dst = append(dst, ' ')
+	dst = append(dst, adapterSyslog.procId...)
+	dst = append(dst, ' ')
+	dst = append(dst, adapterSyslog.msgId...)
+	dst = append(dst, ' ')
+	if len(loggerMsg.fields) == 0 {
+		dst = append(dst, '-')
+	} else {
+		dst = append(dst, '[')
+		dst = append(dst, adapterSyslog.sdId...)
+		for _, field := range loggerMsg.fields {
+			dst = append(dst, ' ')
+			dst = append(dst, syslogSDName(field.Key)...)
+			dst = append(dst, `="`...)
+			start := len(dst)
+			dst = field.appendValue(dst)
+			dst = escapeSyslogSDValue(dst, start)
+			dst = append(dst, '"')
+		}
+		dst = append(dst, ']')
+	}
+	if loggerMsg.body != "" {
+		dst = append(dst, ' ')
+		dst = append(dst, loggerMsg.body...)
+	}
+	return dst
+}
+
+// append RFC 3164 message, hostname is not written to the local unix socket
+//
+//	<14>Mar 23 14:55:07 web-1 app[1024]: login ok user=phachon
+func (adapterSyslog *AdapterSyslog) appendRFC3164(dst []byte, loggerMsg *Entry) []byte {
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(adapterSyslog.priority+loggerMsg.level), 10)
+	dst = append(dst, '>')
+	dst = loggerMsg.time.AppendFormat(dst, time.Stamp)
+	dst = append(dst, ' ')
+	if adapterSyslog.config.Network != SYSLOG_NETWORK_UNIX {
+		dst = append(dst, adapterSyslog.hostname...)
+		dst = append(dst, ' ')
+	}
+	appName := adapterSyslog.appName
+	if len(appName) > 32 {
+		appName = appName[:32]
+	}
+	dst = append(dst, appName...)
+	dst = append(dst, '[')
+	dst = append(dst, adapterSyslog.procId...)
+	dst = append(dst, "]: "...)
+	dst = append(dst, loggerMsg.body...)
+	if len(loggerMsg.fields) > 0 {
+		dst = append(dst, ' ')
+		dst = appendFieldsText(dst, loggerMsg.fields)
+	}
+	return dst
+}
+
+// header field of printable US-ASCII, other characters are replaced by '_', truncated to max, empty is "-"
+func syslogHeaderField(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+	field := []byte(value)
+	for i, c := range field {
+		if c < 33 || c > 126 {
+			field[i] = '_'
+		}
+	}
+	if len(field) > max {
+		field = field[:max]
+	}
+	return string(field)
+}
+
+// SD-NAME of printable US-ASCII except '=', ' ', ']', '"', other characters are replaced by '_', truncated to 32
+func syslogSDName(name string) string {
+	legal := name != "" && len(name) <= 32
+	for i := 0; legal && i < len(name); i++ {
+		legal = syslogSDNameByte(name[i])
+	}
+	if legal {
+		return name
+	}
+	if name == "" {
+		return "_"
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	field := []byte(name)
+	for i, c := range field {
+		if !syslogSDNameByte(c) {
+			field[i] = '_'
+		}
+	}
+	return string(field)
+}
+
+func syslogSDNameByte(c byte) bool {
+	return c >= 33 && c <= 126 && c != '=' && c != ']' && c != '"'
+}
+
+// escape '"', '\\' and ']' of the SD-PARAM value appended from start
+func escapeSyslogSDValue(dst []byte, start int) []byte {
+	if !bytes.ContainsAny(dst[start:], "\"\\]") {
+		return dst
+	}
+	value := string(dst[start:])
+	dst = dst[:start]
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '"' || c == '\\' || c == ']' {
+			dst = append(dst, '\\')
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+func init() {
+	Register(SYSLOG_ADAPTER_NAME, NewAdapterSyslog)
+}
@@ -0,0 +1,339 @@
+package go_logger
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syslog test entry with a fixed time and caller
+func newSyslogTestEntry(body string, keyvals ...interface{}) *Entry {
+	entry := NewEntry(LOGGER_LEVEL_ERROR, body, keyvals...)
+	entry.setTime(time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.UTC))
+	return entry
+}
+
+// read an octet-counting frame "LEN MSG"
+func readSyslogFrame(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	_, err = io.ReadFull(reader, msg)
+	return string(msg), err
+}
+
+func TestAdapterSyslog_Name(t *testing.T) {
+	syslogAdapter := NewAdapterSyslog()
+
+	if syslogAdapter.Name() != SYSLOG_ADAPTER_NAME {
+		t.Error("syslog adapter name error")
+	}
+}
+
+func TestAdapterSyslog_InitError(t *testing.T) {
+
+	configs := []*SyslogConfig{
+		{Network: "sctp", Address: "127.0.0.1:514"},
+		{Network: SYSLOG_NETWORK_UDP},
+		{Network: SYSLOG_NETWORK_UDP, Address: "127.0.0.1:514", RFC: "5425"},
+		{Network: SYSLOG_NETWORK_UDP, Address: "127.0.0.1:514", Facility: "local8"},
+		{Network: SYSLOG_NETWORK_TCP, Address: "127.0.0.1:514", Framing: "lf"},
+		{Network: SYSLOG_NETWORK_UNIX, Address: filepath.Join(os.TempDir(), "go-logger-no-syslog.sock")},
+	}
+	for _, config := range configs {
+		err := NewAdapterSyslog().Init(config)
+		if err == nil {
+			t.Errorf("syslog adapter init %+v must return error", config)
+		}
+	}
+}
+
+func TestAdapterSyslog_WriteUDP(t *testing.T) {
+
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer server.Close()
+
+	syslogAdapter := NewAdapterSyslog()
+	err = syslogAdapter.Init(&SyslogConfig{
+		Network:  SYSLOG_NETWORK_UDP,
+		Address:  server.LocalAddr().String(),
+		Facility: "local0",
+		Hostname: "web 1",
+		AppName:  "app",
+		MsgId:    "login",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer syslogAdapter.(io.Closer).Close()
+
+	err = syslogAdapter.Write(newSyslogTestEntry("login failed", "user", "pha\"chon]", "user id", 7))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := make([]byte, 1024)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := server.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	want := "<131>1 2018-03-23T14:55:07.003123Z web_1 app " + strconv.Itoa(processPid) +
+		` login [fields@32473 user="pha\"chon\]" user_id="7"] login failed`
+	if string(buf[:n]) != want {
+		t.Errorf("syslog udp message error, want %s got %s", want, buf[:n])
+	}
+}
+
+func TestAdapterSyslog_WriteTCP(t *testing.T) {
+
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer server.Close()
+
+	syslogAdapter := NewAdapterSyslog()
+	err = syslogAdapter.Init(&SyslogConfig{
+		Network: SYSLOG_NETWORK_TCP,
+		Address: server.Addr().String(),
+		AppName: "app",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer syslogAdapter.(io.Closer).Close()
+
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer conn.Close()
+
+	err = syslogAdapter.(LoggerBatchWriter).WriteBatch([]*Entry{
+		newSyslogTestEntry("message 1\nline 2"),
+		newSyslogTestEntry("message 2"),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	prefix := "<11>1 2018-03-23T14:55:07.003123Z " + syslogHeaderField(processHostname, 255) + " app " + strconv.Itoa(processPid) + " - - "
+	for _, body := range []string{"message 1\nline 2", "message 2"} {
+		msg, err := readSyslogFrame(reader)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if msg != prefix+body {
+			t.Errorf("syslog tcp message error, want %s got %s", prefix+body, msg)
+		}
+	}
+}
+
+func TestAdapterSyslog_WriteTLS(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-logger"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	server, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer server.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := server.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msg, err := readSyslogFrame(bufio.NewReader(conn))
+		if err != nil {
+			msg = err.Error()
+		}
+		received <- msg
+	}()
+
+	syslogAdapter := NewAdapterSyslog()
+	err = syslogAdapter.Init(&SyslogConfig{
+		Network:   SYSLOG_NETWORK_TLS,
+		Address:   server.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: roots},
+		RFC:       SYSLOG_RFC3164,
+		AppName:   "app",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer syslogAdapter.(io.Closer).Close()
+
+	err = syslogAdapter.Write(newSyslogTestEntry("login failed", "user", "phachon"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	want := "<11>Mar 23 14:55:07 " + syslogHeaderField(processHostname, 255) + " app[" + strconv.Itoa(processPid) + "]: login failed user=phachon"
+	if msg := <-received; msg != want {
+		t.Errorf("syslog tls message error, want %s got %s", want, msg)
+	}
+}
+
+func TestAdapterSyslog_WriteUnix(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "go-logger")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	address := filepath.Join(dir, "log.sock")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: address, Net: "unixgram"})
+	if err != nil {
+		t.Skip("unixgram socket is not supported, " + err.Error())
+	}
+	defer server.Close()
+
+	syslogAdapter := NewAdapterSyslog()
+	err = syslogAdapter.Init(&SyslogConfig{
+		Network:  SYSLOG_NETWORK_UNIX,
+		Address:  address,
+		RFC:      SYSLOG_RFC3164,
+		Facility: "daemon",
+		AppName:  "app",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer syslogAdapter.(io.Closer).Close()
+
+	err = syslogAdapter.Write(newSyslogTestEntry("login failed"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := make([]byte, 1024)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	want := "<27>Mar 23 14:55:07 app[" + strconv.Itoa(processPid) + "]: login failed"
+	if string(buf[:n]) != want {
+		t.Errorf("syslog unix message error, want %s got %s", want, buf[:n])
+	}
+}
+
+func TestAdapterSyslog_Reconnect(t *testing.T) {
+
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer server.Close()
+
+	syslogAdapter := NewAdapterSyslog()
+	err = syslogAdapter.Init(&SyslogConfig{
+		Network: SYSLOG_NETWORK_TCP,
+		Address: server.Addr().String(),
+		Framing: SYSLOG_FRAMING_NON_TRANSPARENT,
+		AppName: "app",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer syslogAdapter.(io.Closer).Close()
+
+	// the server closes the first connection, messages written to it may be lost
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	conn.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := server.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	var reconnected net.Conn
+	for i := 0; i < 50 && reconnected == nil; i++ {
+		syslogAdapter.Write(newSyslogTestEntry("message " + strconv.Itoa(i)))
+		select {
+		case reconnected = <-accepted:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if reconnected == nil {
+		t.Fatal("syslog adapter must reconnect after the connection is closed")
+	}
+	defer reconnected.Close()
+
+	err = syslogAdapter.Write(newSyslogTestEntry("after reconnect"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	reconnected.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(reconnected)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if strings.HasSuffix(line, " - - after reconnect\n") {
+			break
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package go_logger
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	TIME_PRECISION_SECOND      = "s"
+	TIME_PRECISION_MILLISECOND = "ms"
+	TIME_PRECISION_MICROSECOND = "us"
+	TIME_PRECISION_NANOSECOND  = "ns"
+)
+
+var timePrecisionMapping = map[string]time.Duration{
+	TIME_PRECISION_SECOND:      time.Second,
+	TIME_PRECISION_MILLISECOND: time.Millisecond,
+	TIME_PRECISION_MICROSECOND: time.Microsecond,
+	TIME_PRECISION_NANOSECOND:  time.Nanosecond,
+}
+
+// output time of %time%, %time_unix% tokens and time, time_unix json keys
+type timeEncoder struct {
+	layout    string
+	location  *time.Location
+	precision time.Duration
+}
+
+// default time encoder, RFC3339Nano in local time, millisecond precision
+var defaultTimeEncoder = &timeEncoder{
+	layout:    time.RFC3339Nano,
+	location:  time.Local,
+	precision: time.Millisecond,
+}
+
+//new time encoder of output config
+//params : layout, default time.RFC3339Nano; zone "UTC" | "Local" | IANA name, default "Local"; precision "s" | "ms" | "us" | "ns", default "ms"
+//return : *timeEncoder, error
+func newTimeEncoder(layout string, zone string, precision string) (*timeEncoder, error) {
+	encoder := &timeEncoder{
+		layout:    layout,
+		location:  time.Local,
+		precision: time.Millisecond,
+	}
+	if encoder.layout == "" {
+		encoder.layout = time.RFC3339Nano
+	}
+	if zone != "" {
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, errors.New("config TimeZone " + zone + " is unknown!")
+		}
+		encoder.location = location
+	}
+	if precision != "" {
+		duration, ok := timePrecisionMapping[precision]
+		if !ok {
+			return nil, errors.New("config TimePrecision must be one of the 's', 'ms', 'us', 'ns'!")
+		}
+		encoder.precision = duration
+	}
+	return encoder, nil
+}
+
+//append time formatted by layout in zone, truncated to precision
+func (encoder *timeEncoder) appendTime(dst []byte, t time.Time) []byte {
+	return t.In(encoder.location).Truncate(encoder.precision).AppendFormat(dst, encoder.layout)
+}
+
+//unix time in precision unit
+func (encoder *timeEncoder) unix(t time.Time) int64 {
+	return t.UnixNano() / int64(encoder.precision)
+}
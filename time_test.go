@@ -0,0 +1,65 @@
+package go_logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewTimeEncoder(t *testing.T) {
+
+	now := time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.UTC)
+
+	encoder, err := newTimeEncoder("", "UTC", TIME_PRECISION_MICROSECOND)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if str := string(encoder.appendTime(nil, now)); str != "2018-03-23T14:55:07.003123Z" {
+		t.Errorf("time encoder format error, got %s", str)
+	}
+	if encoder.unix(now) != now.UnixNano()/1e3 {
+		t.Errorf("time encoder unix error, got %d", encoder.unix(now))
+	}
+
+	encoder, err = newTimeEncoder("2006-01-02 15:04:05.000 MST", "Asia/Shanghai", TIME_PRECISION_SECOND)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if str := string(encoder.appendTime(nil, now)); str != "2018-03-23 22:55:07.000 CST" {
+		t.Errorf("time encoder zone error, got %s", str)
+	}
+	if encoder.unix(now) != now.Unix() {
+		t.Errorf("time encoder unix second error, got %d", encoder.unix(now))
+	}
+
+	_, err = newTimeEncoder("", "Mars/Olympus", "")
+	if err == nil {
+		t.Error("time encoder unknown zone must return error")
+	}
+	_, err = newTimeEncoder("", "", "m")
+	if err == nil {
+		t.Error("time encoder illegal precision must return error")
+	}
+}
+
+func TestAdapterConsole_WriteTime(t *testing.T) {
+
+	logger := NewLogger()
+	logger.Detach("console")
+	err := logger.Attach("console", LOGGER_LEVEL_DEBUG, &ConsoleConfig{
+		Format:        "%time% %time_unix% %millisecond% %body%",
+		TimeZone:      "UTC",
+		TimePrecision: TIME_PRECISION_NANOSECOND,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	entry := NewEntry(LOGGER_LEVEL_INFO, "time message")
+	entry.setTime(time.Date(2018, 3, 23, 14, 55, 7, 3123456, time.UTC))
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.bs = logger.Output("console").(*AdapterConsole).encoder.Encode(buf.bs, entry)
+	if string(buf.bs) != "2018-03-23T14:55:07.003123456Z 1521816907003123456 1521816907003 time message" {
+		t.Errorf("console time tokens error, got %s", buf.bs)
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
@@ -108,6 +109,34 @@ func (misc *Misc) HttpPost(queryUrl string, queryValues map[string]string, heade
 	return string(bodyByte), code, nil
 }
 
+//http post request with body
+func (misc *Misc) HttpPostBody(queryUrl string, body []byte, contentType string, headerValues map[string]string, timeout int) (respBody string, code int, err error) {
+	req, err := http.NewRequest("POST", queryUrl, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", contentType)
+	if (headerValues != nil) && (len(headerValues) > 0) {
+		for key, value := range headerValues {
+			req.Header.Set(key, value)
+		}
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	code = resp.StatusCode
+	defer resp.Body.Close()
+
+	bodyByte, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	return string(bodyByte), code, nil
+}
+
 // rand string
 func (m *Misc) RandString(strlen int) string {
 	codes := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"